@@ -47,14 +47,14 @@ func main() {
 	}
 
 	// Initialize APR service without GraphQL clients (they will be created dynamically)
-	aprService := services.NewAPRService(db)
+	aprService := services.NewAPRService(db, cfg)
 
 	// Initialize scheduler for background tasks
 	taskScheduler := scheduler.NewScheduler(db, cfg, aprService)
 	taskScheduler.Start()
 
 	// Initialize router
-	r := router.SetupRouter(db)
+	r := router.SetupRouter(db, aprService)
 
 	// Start server
 	srv := &http.Server{
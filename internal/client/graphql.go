@@ -2,16 +2,42 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"time"
 )
 
+// DefaultTimeout bounds a single request when the client is created without a timeout
+const DefaultTimeout = 30 * time.Second
+
+// sharedTransport is reused by all clients so connections to the subgraphs are pooled across runs.
+// It sets no response timeout, responses are bounded by the per-request timeout of each client.
+var sharedTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   10,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
 // GraphQLClient for making GraphQL requests
 type GraphQLClient struct {
-	URL    string
-	APIKey string
+	URL     string
+	APIKey  string
+	Timeout time.Duration // Per attempt timeout
+	Retry   RetryPolicy
+
+	httpClient *http.Client
 }
 
 // GraphQLRequest represents a GraphQL request
@@ -26,22 +52,72 @@ type GraphQLResponse struct {
 	Errors []GraphQLError `json:"errors,omitempty"`
 }
 
+// rawResponse is a GraphQL response with its data left undecoded
+type rawResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []GraphQLError  `json:"errors,omitempty"`
+}
+
 // GraphQLError represents a GraphQL error
 type GraphQLError struct {
 	Message string        `json:"message"`
 	Path    []interface{} `json:"path,omitempty"`
 }
 
-// NewGraphQLClient creates a new GraphQL client
-func NewGraphQLClient(url, apiKey string) *GraphQLClient {
+// NewGraphQLClient creates a new GraphQL client, a non-positive timeout uses DefaultTimeout
+func NewGraphQLClient(url, apiKey string, timeout time.Duration, retry RetryPolicy) *GraphQLClient {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
 	return &GraphQLClient{
-		URL:    url,
-		APIKey: apiKey,
+		URL:        url,
+		APIKey:     apiKey,
+		Timeout:    timeout,
+		Retry:      retry,
+		httpClient: &http.Client{Transport: sharedTransport},
 	}
 }
 
 // Execute executes a GraphQL query
 func (c *GraphQLClient) Execute(query string, variables map[string]interface{}) (*GraphQLResponse, error) {
+	return c.ExecuteContext(context.Background(), query, variables)
+}
+
+// ExecuteContext executes a GraphQL query, aborting it when ctx is done. Transient failures are
+// retried according to the client retry policy, each attempt bounded by the client timeout.
+func (c *GraphQLClient) ExecuteContext(ctx context.Context, query string, variables map[string]interface{}) (*GraphQLResponse, error) {
+	raw, err := c.executeWithRetry(ctx, query, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GraphQLResponse{Errors: raw.Errors}
+	if len(raw.Data) > 0 {
+		if err := json.Unmarshal(raw.Data, &result.Data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response data: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// ExecuteInto executes a GraphQL query like ExecuteContext and decodes its data directly into out
+func (c *GraphQLClient) ExecuteInto(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	raw, err := c.executeWithRetry(ctx, query, variables)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(raw.Data, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response data: %w", err)
+	}
+
+	return nil
+}
+
+// executeWithRetry sends a query, retrying transient failures according to the client retry policy
+func (c *GraphQLClient) executeWithRetry(ctx context.Context, query string, variables map[string]interface{}) (*rawResponse, error) {
 	reqBody := GraphQLRequest{
 		Query:     query,
 		Variables: variables,
@@ -52,7 +128,36 @@ func (c *GraphQLClient) Execute(query string, variables map[string]interface{})
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", c.URL, bytes.NewBuffer(jsonData))
+	for retry := 0; ; retry++ {
+		result, err := c.execute(ctx, jsonData)
+		if err == nil {
+			return result, nil
+		}
+
+		retryable, retryAfter := isRetryable(err)
+		if !retryable || retry >= c.Retry.MaxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		timer := time.NewTimer(c.Retry.backoff(retry, retryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("retry aborted: %w: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// execute sends a single request, wrapping failures that may succeed on retry in retryableError
+func (c *GraphQLClient) execute(ctx context.Context, jsonData []byte) (*rawResponse, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.URL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -62,26 +167,51 @@ func (c *GraphQLClient) Execute(query string, variables map[string]interface{})
 		req.Header.Set("api-key", c.APIKey)
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	httpClient := c.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: sharedTransport}
+	}
+
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		// Connection failures and attempt timeouts are transient
+		return nil, &retryableError{err: fmt.Errorf("failed to execute request: %w", err)}
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, &retryableError{err: fmt.Errorf("failed to read response: %w", err)}
 	}
 
-	var result GraphQLResponse
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unexpected status %d: %s", resp.StatusCode, truncate(string(body), 200))
+		if isRetryableStatus(resp.StatusCode) {
+			return nil, &retryableError{err: err, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+		}
+		return nil, err
+	}
+
+	var result rawResponse
 	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	if len(result.Errors) > 0 {
-		return nil, fmt.Errorf("graphql errors: %v", result.Errors)
+		err := fmt.Errorf("graphql errors: %v", result.Errors)
+		if isRetryableGraphQLError(result.Errors) {
+			return nil, &retryableError{err: err}
+		}
+		return nil, err
 	}
 
 	return &result, nil
 }
+
+// truncate shortens a response body included in an error message
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length] + "..."
+}
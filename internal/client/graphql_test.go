@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExecuteContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api-key") != "secret" {
+			t.Errorf("api-key header = %q, expected %q", r.Header.Get("api-key"), "secret")
+		}
+		w.Write([]byte(`{"data": {"bundles": [{"id": "1"}]}}`))
+	}))
+	defer server.Close()
+
+	client := NewGraphQLClient(server.URL, "secret", time.Second, RetryPolicy{})
+	result, err := client.ExecuteContext(context.Background(), "query { bundles { id } }", nil)
+	if err != nil {
+		t.Fatalf("ExecuteContext() returned error: %v", err)
+	}
+	if result.Data == nil {
+		t.Errorf("ExecuteContext() returned no data")
+	}
+}
+
+func TestExecuteContextTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewGraphQLClient(server.URL, "", 50*time.Millisecond, RetryPolicy{})
+	start := time.Now()
+	if _, err := client.ExecuteContext(context.Background(), "query { bundles { id } }", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ExecuteContext() error = %v, expected deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ExecuteContext() returned after %s, expected the client timeout", elapsed)
+	}
+}
+
+func TestExecuteContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	client := NewGraphQLClient(server.URL, "", time.Minute, RetryPolicy{})
+	if _, err := client.ExecuteContext(ctx, "query { bundles { id } }", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExecuteContext() error = %v, expected context canceled", err)
+	}
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultPageSize is the largest page the subgraphs return
+const DefaultPageSize = 1000
+
+// PageQuery describes an entity query paginated by id. The query must accept $first and $id_gt
+// and order its results by id ascending.
+type PageQuery[R any, T any] struct {
+	Query     string
+	Variables map[string]interface{} // Extra variables sent with every page
+	PageSize  int                    // DefaultPageSize if not set
+
+	Items func(response R) []T // Entities of a decoded page response
+	ID    func(item T) string  // Id of an entity, used as id_gt of the next page
+}
+
+// PageInfo describes a fetched page
+type PageInfo struct {
+	Number  int // 1-based
+	Items   int
+	Latency time.Duration
+}
+
+// PaginationStats summarizes a paginated fetch
+type PaginationStats struct {
+	Pages    int
+	Items    int
+	Duration time.Duration
+}
+
+// Paginate fetches every page of a query in id order, passing each page to onPage as soon as it is decoded
+// so callers don't have to hold all entities in memory. An error returned by onPage stops the fetch.
+func Paginate[R any, T any](ctx context.Context, c *GraphQLClient, q PageQuery[R, T], onPage func(items []T, page PageInfo) error) (PaginationStats, error) {
+	pageSize := q.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	var stats PaginationStats
+	start := time.Now()
+	lastID := "0"
+
+	for {
+		variables := make(map[string]interface{}, len(q.Variables)+2)
+		for key, value := range q.Variables {
+			variables[key] = value
+		}
+		variables["first"] = pageSize
+		variables["id_gt"] = lastID
+
+		pageStart := time.Now()
+		var response R
+		if err := c.ExecuteInto(ctx, q.Query, variables, &response); err != nil {
+			stats.Duration = time.Since(start)
+			return stats, fmt.Errorf("failed to fetch page %d: %w", stats.Pages+1, err)
+		}
+
+		items := q.Items(response)
+		if len(items) == 0 {
+			break
+		}
+
+		stats.Pages++
+		stats.Items += len(items)
+		page := PageInfo{Number: stats.Pages, Items: len(items), Latency: time.Since(pageStart)}
+		if err := onPage(items, page); err != nil {
+			stats.Duration = time.Since(start)
+			return stats, err
+		}
+
+		if len(items) < pageSize {
+			break
+		}
+		lastID = q.ID(items[len(items)-1])
+	}
+
+	stats.Duration = time.Since(start)
+	return stats, nil
+}
+
+// FetchAll fetches every page of a query and returns all entities
+func FetchAll[R any, T any](ctx context.Context, c *GraphQLClient, q PageQuery[R, T]) ([]T, PaginationStats, error) {
+	var all []T
+	stats, err := Paginate(ctx, c, q, func(items []T, page PageInfo) error {
+		all = append(all, items...)
+		return nil
+	})
+	if err != nil {
+		return nil, stats, err
+	}
+
+	return all, stats, nil
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type testEntity struct {
+	ID string `json:"id"`
+}
+
+type testEntitiesResponse struct {
+	Entities []testEntity `json:"entities"`
+}
+
+var testEntitiesQuery = PageQuery[testEntitiesResponse, testEntity]{
+	Query:     "query entities($first: Int!, $id_gt: String!) { entities(first: $first, where: {id_gt: $id_gt}) { id } }",
+	Variables: map[string]interface{}{"pool": "0x1"},
+	PageSize:  2,
+	Items:     func(response testEntitiesResponse) []testEntity { return response.Entities },
+	ID:        func(entity testEntity) string { return entity.ID },
+}
+
+// entitiesServer serves entities with ids 1..count, paginated by first and id_gt
+func entitiesServer(t *testing.T, count int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request GraphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if request.Variables["pool"] != "0x1" {
+			t.Errorf("extra variable pool = %v, expected 0x1", request.Variables["pool"])
+		}
+
+		first := int(request.Variables["first"].(float64))
+		idGt, _ := strconv.Atoi(request.Variables["id_gt"].(string))
+
+		var response testEntitiesResponse
+		for id := idGt + 1; id <= count && len(response.Entities) < first; id++ {
+			response.Entities = append(response.Entities, testEntity{ID: strconv.Itoa(id)})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": response})
+	}))
+}
+
+func TestFetchAll(t *testing.T) {
+	tests := []struct {
+		count         int
+		expectedPages int
+	}{
+		{count: 0, expectedPages: 0},
+		{count: 1, expectedPages: 1},
+		{count: 4, expectedPages: 2},
+		{count: 5, expectedPages: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d entities", tt.count), func(t *testing.T) {
+			server := entitiesServer(t, tt.count)
+			defer server.Close()
+
+			client := NewGraphQLClient(server.URL, "", time.Second, RetryPolicy{})
+			entities, stats, err := FetchAll(context.Background(), client, testEntitiesQuery)
+			if err != nil {
+				t.Fatalf("FetchAll() returned error: %v", err)
+			}
+
+			if len(entities) != tt.count {
+				t.Fatalf("FetchAll() returned %d entities, expected %d", len(entities), tt.count)
+			}
+			for i, entity := range entities {
+				if entity.ID != strconv.Itoa(i+1) {
+					t.Errorf("entity %d id = %s, expected %d", i, entity.ID, i+1)
+				}
+			}
+			if stats.Pages != tt.expectedPages || stats.Items != tt.count {
+				t.Errorf("FetchAll() stats = %d pages, %d items, expected %d pages, %d items", stats.Pages, stats.Items, tt.expectedPages, tt.count)
+			}
+		})
+	}
+}
+
+func TestPaginateStopsOnCallbackError(t *testing.T) {
+	server := entitiesServer(t, 10)
+	defer server.Close()
+
+	stop := errors.New("stop")
+	client := NewGraphQLClient(server.URL, "", time.Second, RetryPolicy{})
+	stats, err := Paginate(context.Background(), client, testEntitiesQuery, func(entities []testEntity, page PageInfo) error {
+		if page.Number == 2 {
+			return stop
+		}
+		return nil
+	})
+
+	if !errors.Is(err, stop) {
+		t.Errorf("Paginate() error = %v, expected callback error", err)
+	}
+	if stats.Pages != 2 {
+		t.Errorf("Paginate() fetched %d pages, expected 2", stats.Pages)
+	}
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried with exponential backoff
+type RetryPolicy struct {
+	MaxRetries     int           // Retries after the first attempt, 0 disables retrying
+	InitialBackoff time.Duration // Backoff before the first retry, doubled for every following one
+	MaxBackoff     time.Duration // Upper bound of the backoff, Retry-After may exceed it
+}
+
+// DefaultRetryPolicy is used for networks without a retry configuration
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+}
+
+// MaxRetryAfter caps the delay a server can request with Retry-After, so a single response can't park an update for hours
+const MaxRetryAfter = time.Minute
+
+// WithDefaults fills the backoff durations left at zero from DefaultRetryPolicy
+func (p RetryPolicy) WithDefaults() RetryPolicy {
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+	return p
+}
+
+// retryableGraphQLErrors are fragments of GraphQL error messages caused by indexer hiccups rather than the query
+var retryableGraphQLErrors = []string{
+	"bad indexers",
+	"indexer",
+	"timeout",
+	"timed out",
+	"too many requests",
+	"rate limit",
+	"unavailable",
+	"store error",
+	"try again",
+}
+
+// retryableError marks a failure that may succeed when the request is sent again
+type retryableError struct {
+	err        error
+	retryAfter time.Duration // Delay requested by the server, 0 if none
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// isRetryable reports whether err may succeed on retry and the delay the server asked for
+func isRetryable(err error) (bool, time.Duration) {
+	var retryable *retryableError
+	if errors.As(err, &retryable) {
+		return true, retryable.retryAfter
+	}
+	return false, 0
+}
+
+// isRetryableStatus reports whether an HTTP status is a transient server or rate limit failure
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRetryableGraphQLError reports whether all errors of a response are transient indexer failures.
+// Validation errors of the query itself are permanent.
+func isRetryableGraphQLError(graphqlErrors []GraphQLError) bool {
+	if len(graphqlErrors) == 0 {
+		return false
+	}
+
+	for _, graphqlError := range graphqlErrors {
+		message := strings.ToLower(graphqlError.Message)
+
+		retryable := false
+		for _, fragment := range retryableGraphQLErrors {
+			if strings.Contains(message, fragment) {
+				retryable = true
+				break
+			}
+		}
+		if !retryable {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+
+	return 0
+}
+
+// backoff returns the delay before the given retry (0-based), with jitter so clients
+// failing together don't retry together. A longer Retry-After from the server wins, up to MaxRetryAfter.
+func (p RetryPolicy) backoff(retry int, retryAfter time.Duration) time.Duration {
+	delay := p.InitialBackoff
+	for i := 0; i < retry && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+
+	// Equal jitter keeps at least half of the backoff
+	if delay > 0 {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+
+	if retryAfter > MaxRetryAfter {
+		retryAfter = MaxRetryAfter
+	}
+	if retryAfter > delay {
+		return retryAfter
+	}
+	return delay
+}
@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+}
+
+// fakeSubgraph answers every request with the next response, repeating the last one when it runs out
+type fakeSubgraph struct {
+	responses []func(w http.ResponseWriter)
+	requests  atomic.Int32
+}
+
+func (f *fakeSubgraph) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	i := int(f.requests.Add(1)) - 1
+	if i >= len(f.responses) {
+		i = len(f.responses) - 1
+	}
+	f.responses[i](w)
+}
+
+func status(code int, headers ...string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.WriteHeader(code)
+	}
+}
+
+func body(value string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Write([]byte(value))
+	}
+}
+
+const okBody = `{"data": {"pools": []}}`
+
+func TestExecuteContextRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		responses        []func(w http.ResponseWriter)
+		expectError      bool
+		expectedRequests int32
+	}{
+		{
+			name:             "retries 5xx until success",
+			responses:        []func(w http.ResponseWriter){status(http.StatusBadGateway), status(http.StatusServiceUnavailable), body(okBody)},
+			expectedRequests: 3,
+		},
+		{
+			name:             "retries rate limit",
+			responses:        []func(w http.ResponseWriter){status(http.StatusTooManyRequests, "Retry-After", "0"), body(okBody)},
+			expectedRequests: 2,
+		},
+		{
+			name:             "retries indexer errors",
+			responses:        []func(w http.ResponseWriter){body(`{"errors": [{"message": "bad indexers: all indexers are behind"}]}`), body(okBody)},
+			expectedRequests: 2,
+		},
+		{
+			name:             "does not retry query validation errors",
+			responses:        []func(w http.ResponseWriter){body(`{"errors": [{"message": "Type Query has no field poolz"}]}`), body(okBody)},
+			expectError:      true,
+			expectedRequests: 1,
+		},
+		{
+			name:             "does not retry client errors",
+			responses:        []func(w http.ResponseWriter){status(http.StatusBadRequest), body(okBody)},
+			expectError:      true,
+			expectedRequests: 1,
+		},
+		{
+			name:             "gives up after max retries",
+			responses:        []func(w http.ResponseWriter){status(http.StatusServiceUnavailable)},
+			expectError:      true,
+			expectedRequests: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subgraph := &fakeSubgraph{responses: tt.responses}
+			server := httptest.NewServer(subgraph)
+			defer server.Close()
+
+			client := NewGraphQLClient(server.URL, "", time.Second, testRetryPolicy)
+			_, err := client.ExecuteContext(context.Background(), "query { pools { id } }", nil)
+
+			if tt.expectError && err == nil {
+				t.Errorf("ExecuteContext() expected error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("ExecuteContext() returned error: %v", err)
+			}
+			if requests := subgraph.requests.Load(); requests != tt.expectedRequests {
+				t.Errorf("subgraph received %d requests, expected %d", requests, tt.expectedRequests)
+			}
+		})
+	}
+}
+
+func TestExecuteContextHonorsRetryAfter(t *testing.T) {
+	subgraph := &fakeSubgraph{responses: []func(w http.ResponseWriter){status(http.StatusTooManyRequests, "Retry-After", "1"), body(okBody)}}
+	server := httptest.NewServer(subgraph)
+	defer server.Close()
+
+	client := NewGraphQLClient(server.URL, "", time.Second, testRetryPolicy)
+	start := time.Now()
+	if _, err := client.ExecuteContext(context.Background(), "query { pools { id } }", nil); err != nil {
+		t.Fatalf("ExecuteContext() returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("ExecuteContext() retried after %s, expected to wait for Retry-After", elapsed)
+	}
+}
+
+func TestExecuteContextStopsRetryingWhenCancelled(t *testing.T) {
+	subgraph := &fakeSubgraph{responses: []func(w http.ResponseWriter){status(http.StatusServiceUnavailable, "Retry-After", "60")}}
+	server := httptest.NewServer(subgraph)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	client := NewGraphQLClient(server.URL, "", time.Second, testRetryPolicy)
+	if _, err := client.ExecuteContext(ctx, "query { pools { id } }", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ExecuteContext() error = %v, expected deadline exceeded", err)
+	}
+	if requests := subgraph.requests.Load(); requests != 1 {
+		t.Errorf("subgraph received %d requests, expected 1", requests)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"-1", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-30 * time.Second).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if result := parseRetryAfter(tt.value, now); result != tt.expected {
+			t.Errorf("parseRetryAfter(%q) = %s, expected %s", tt.value, result, tt.expected)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for retry, expected := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		delay := policy.backoff(retry, 0)
+		if delay < expected/2 || delay > expected {
+			t.Errorf("backoff(%d) = %s, expected between %s and %s", retry, delay, expected/2, expected)
+		}
+	}
+
+	if delay := policy.backoff(0, 5*time.Second); delay != 5*time.Second {
+		t.Errorf("backoff() with Retry-After = %s, expected %s", delay, 5*time.Second)
+	}
+	if delay := policy.backoff(0, 3*time.Hour); delay != MaxRetryAfter {
+		t.Errorf("backoff() with a 3h Retry-After = %s, expected %s", delay, MaxRetryAfter)
+	}
+}
+
+func TestRetryPolicyWithDefaults(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 5}.WithDefaults()
+	if policy.MaxRetries != 5 || policy.InitialBackoff != DefaultRetryPolicy.InitialBackoff || policy.MaxBackoff != DefaultRetryPolicy.MaxBackoff {
+		t.Errorf("WithDefaults() = %+v, expected max retries 5 with the default backoff", policy)
+	}
+
+	policy = RetryPolicy{MaxRetries: 1, InitialBackoff: time.Second, MaxBackoff: 2 * time.Second}.WithDefaults()
+	if policy.InitialBackoff != time.Second || policy.MaxBackoff != 2*time.Second {
+		t.Errorf("WithDefaults() = %+v, expected the configured backoff to be kept", policy)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -17,6 +18,19 @@ type Config struct {
 	LogLevel         string    `mapstructure:"log_level"`
 	Networks         []Network `mapstructure:"networks"`
 	APRUpdateMinutes int       `mapstructure:"apr_update_minutes"`
+	FeeWindowDays    int       `mapstructure:"fee_window_days"` // Number of poolDayDatas days used for windowed fee APR
+
+	// Number of poolHourDatas hours the rolling daily fees are computed over
+	FeeRollingWindowHours int `mapstructure:"fee_rolling_window_hours"`
+
+	// Deposit size in USD the marginal farming APR is computed for
+	ReferenceDepositUSD float64 `mapstructure:"reference_deposit_usd"`
+
+	// How often rewards are assumed to be compounded for APY: daily or weekly
+	CompoundingFrequency string `mapstructure:"compounding_frequency"`
+
+	// Number of subgraph datasets of a network fetched concurrently during an update
+	MaxConcurrentFetches int `mapstructure:"max_concurrent_fetches"`
 }
 
 type DBConfig struct {
@@ -33,6 +47,40 @@ type Network struct {
 	AnalyticsSubgraphURL string `mapstructure:"analytics_subgraph_url"`
 	FarmingSubgraphURL   string `mapstructure:"subgraph_farming_url"`
 	APIKey               string `mapstructure:"api_key"`
+
+	// Timeout of a single subgraph request, defaults to 30 seconds
+	RequestTimeoutSeconds int `mapstructure:"request_timeout_seconds"`
+
+	// Retries of failed subgraph requests, nil uses the client defaults
+	Retry *RetryConfig `mapstructure:"retry"`
+
+	// Positions below these thresholds are ignored by the realistic max APR
+	MaxAPRFilter MaxAPRFilter `mapstructure:"max_apr_filter"`
+}
+
+// MaxAPRFilter sets the minimum size and range of a position counted towards the realistic max APR.
+// Zero disables a threshold.
+type MaxAPRFilter struct {
+	MinPositionValueUSD float64 `mapstructure:"min_position_value_usd"`
+	MinRangeWidthTicks  int     `mapstructure:"min_range_width_ticks"`
+	MinLiquidityShare   float64 `mapstructure:"min_liquidity_share"` // Fraction (0-1) of the active liquidity
+}
+
+// RetryConfig sets how transient subgraph failures (5xx, 429, indexer errors) are retried
+type RetryConfig struct {
+	MaxRetries       int `mapstructure:"max_retries"`        // 0 disables retrying
+	InitialBackoffMs int `mapstructure:"initial_backoff_ms"` // Doubled on every retry, 0 uses the default
+	MaxBackoffMs     int `mapstructure:"max_backoff_ms"`     // 0 uses the default
+}
+
+// GetNetwork returns the configuration of the network with the given title
+func (c *Config) GetNetwork(title string) (Network, bool) {
+	for _, network := range c.Networks {
+		if network.Title == title {
+			return network, true
+		}
+	}
+	return Network{}, false
 }
 
 func (db *DBConfig) GetDSN() string {
@@ -108,5 +156,53 @@ func LoadConfig(path string) (*Config, error) {
 		config.Port = "8080"
 	}
 
+	// Default fee window covers the longest fee APR window (30d)
+	if config.FeeWindowDays <= 0 {
+		config.FeeWindowDays = 30
+	}
+
+	for i := range config.Networks {
+		if config.Networks[i].RequestTimeoutSeconds <= 0 {
+			config.Networks[i].RequestTimeoutSeconds = 30
+		}
+	}
+
+	// Default rolling window covers the exact last 24 hours
+	if config.FeeRollingWindowHours <= 0 {
+		config.FeeRollingWindowHours = 24
+	}
+
+	if config.ReferenceDepositUSD <= 0 {
+		config.ReferenceDepositUSD = 1000
+	}
+
+	if config.MaxConcurrentFetches <= 0 {
+		config.MaxConcurrentFetches = 4
+	}
+
+	if config.CompoundingFrequency == "" {
+		config.CompoundingFrequency = "daily"
+	}
+	if config.CompoundingPeriodsPerYear() == 0 {
+		return nil, fmt.Errorf("invalid compounding_frequency %q, expected daily or weekly", config.CompoundingFrequency)
+	}
+
 	return &config, nil
 }
+
+// CompoundingPeriodsPerYear returns how many times a year APY compounds, 0 for an unknown frequency
+func (c *Config) CompoundingPeriodsPerYear() int {
+	switch c.CompoundingFrequency {
+	case "daily":
+		return 365
+	case "weekly":
+		return 52
+	default:
+		return 0
+	}
+}
+
+// RequestTimeout returns the timeout of a single subgraph request of the network
+func (n Network) RequestTimeout() time.Duration {
+	return time.Duration(n.RequestTimeoutSeconds) * time.Second
+}
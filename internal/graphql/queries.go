@@ -21,3 +21,12 @@ var TokensQuery string
 
 //go:embed pool_day_datas.graphql
 var PoolDayDatasQuery string
+
+//go:embed pool_hour_datas.graphql
+var PoolHourDatasQuery string
+
+//go:embed bundle.graphql
+var BundleQuery string
+
+//go:embed meta.graphql
+var MetaQuery string
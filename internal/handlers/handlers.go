@@ -3,7 +3,10 @@ package handlers
 import (
 	"algebra-apr-backend/internal/logger"
 	"algebra-apr-backend/internal/models"
+	"algebra-apr-backend/internal/services"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -11,18 +14,31 @@ import (
 )
 
 type Handler struct {
-	db *gorm.DB
+	db         *gorm.DB
+	aprService *services.APRService
 }
 
-func NewHandler(db *gorm.DB) *Handler {
+func NewHandler(db *gorm.DB, aprService *services.APRService) *Handler {
 	return &Handler{
-		db: db,
+		db:         db,
+		aprService: aprService,
 	}
 }
 
-// GET /api/pools/apr?network=Polygon
+// GET /api/pools/apr?network=Polygon&window=1d&metric=apr
 func (h *Handler) GetPoolsAPR(c *gin.Context) {
 	networkName := c.DefaultQuery("network", "Polygon")
+	window := c.DefaultQuery("window", "1d")
+
+	if window != "1d" && window != "7d" && window != "30d" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid window, expected one of 1d, 7d, 30d"})
+		return
+	}
+
+	metric, ok := parseMetric(c)
+	if !ok {
+		return
+	}
 
 	var pools []models.Pool
 	result := h.db.Preload("Network").Joins("JOIN networks ON pools.network_id = networks.id").Where("networks.title = ?", networkName).Find(&pools)
@@ -34,8 +50,16 @@ func (h *Handler) GetPoolsAPR(c *gin.Context) {
 
 	response := make(map[string]interface{})
 	for _, pool := range pools {
-		if pool.LastAPR != nil {
-			response[pool.Address] = *pool.LastAPR
+		apr := selectMetric(metric, pool.LastAPR, pool.APY)
+		switch window {
+		case "7d":
+			apr = selectMetric(metric, pool.APR7d, pool.APY7d)
+		case "30d":
+			apr = selectMetric(metric, pool.APR30d, pool.APY30d)
+		}
+
+		if apr != nil {
+			response[pool.Address] = *apr
 		} else {
 			response[pool.Address] = 0.0
 		}
@@ -44,10 +68,15 @@ func (h *Handler) GetPoolsAPR(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// GET /api/pools/max-apr?network=Polygon
+// GET /api/pools/max-apr?network=Polygon&stat=max&metric=apr
 func (h *Handler) GetPoolsMaxAPR(c *gin.Context) {
 	networkName := c.DefaultQuery("network", "Polygon")
 
+	stat, metric, ok := parseMaxAPRParams(c)
+	if !ok {
+		return
+	}
+
 	var pools []models.Pool
 	result := h.db.Preload("Network").Joins("JOIN networks ON pools.network_id = networks.id").Where("networks.title = ?", networkName).Find(&pools)
 	if result.Error != nil {
@@ -58,80 +87,243 @@ func (h *Handler) GetPoolsMaxAPR(c *gin.Context) {
 
 	response := make(map[string]interface{})
 	for _, pool := range pools {
-		if pool.MaxAPR != nil {
-			response[pool.Address] = *pool.MaxAPR
-		} else {
-			response[pool.Address] = 0.0
-		}
+		maxAPR := selectAPRStat(stat, pool.APRP25, pool.APRMedian, pool.APRP75, pool.APRP90, pool.MaxAPR, pool.RealisticMaxAPR)
+		maxAPY := selectAPRStat(stat, pool.APYP25, pool.APYMedian, pool.APYP75, pool.APYP90, pool.MaxAPY, pool.RealisticMaxAPY)
+		response[pool.Address] = valueOrZero(selectMetric(metric, maxAPR, maxAPY))
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// GET /api/eternal-farmings/apr?network=Polygon
+// GET /api/eternal-farmings/apr?network=Polygon&status=active&metric=apr
 func (h *Handler) GetEternalFarmingsAPR(c *gin.Context) {
 	networkName := c.DefaultQuery("network", "Polygon")
 
-	var farmings []models.Farming
-	result := h.db.Preload("Network").Joins("JOIN networks ON farmings.network_id = networks.id").Where("networks.title = ?", networkName).Find(&farmings)
-	if result.Error != nil {
-		logger.Logger.Error("Failed to fetch eternal farmings", zap.Error(result.Error))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch eternal farmings"})
+	metric, ok := parseMetric(c)
+	if !ok {
+		return
+	}
+
+	farmings, ok := h.findFarmings(c, networkName)
+	if !ok {
 		return
 	}
 
 	response := make(map[string]interface{})
 	for _, farming := range farmings {
-		if farming.LastAPR != nil {
-			response[farming.Hash] = *farming.LastAPR
-		} else {
-			response[farming.Hash] = 0.0
-		}
+		response[farming.Hash] = valueOrZero(selectMetric(metric, farming.LastAPR, farming.APY))
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// GET /api/eternal-farmings/max-apr?network=Polygon
+// GET /api/eternal-farmings/marginal-apr?network=Polygon&status=active&metric=apr
+func (h *Handler) GetFarmingsMarginalAPR(c *gin.Context) {
+	networkName := c.DefaultQuery("network", "Polygon")
+
+	metric, ok := parseMetric(c)
+	if !ok {
+		return
+	}
+
+	farmings, ok := h.findFarmings(c, networkName)
+	if !ok {
+		return
+	}
+
+	response := make(map[string]interface{})
+	for _, farming := range farmings {
+		response[farming.Hash] = valueOrZero(selectMetric(metric, farming.MarginalAPR, farming.MarginalAPY))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GET /api/eternal-farmings/max-apr?network=Polygon&status=active&stat=max&metric=apr
 func (h *Handler) GetFarmingsMaxAPR(c *gin.Context) {
 	networkName := c.DefaultQuery("network", "Polygon")
 
-	var farmings []models.Farming
-	result := h.db.Preload("Network").Joins("JOIN networks ON farmings.network_id = networks.id").Where("networks.title = ?", networkName).Find(&farmings)
-	if result.Error != nil {
-		logger.Logger.Error("Failed to fetch eternal farmings", zap.Error(result.Error))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch eternal farmings"})
+	stat, metric, ok := parseMaxAPRParams(c)
+	if !ok {
+		return
+	}
+
+	farmings, ok := h.findFarmings(c, networkName)
+	if !ok {
 		return
 	}
 
 	response := make(map[string]interface{})
 	for _, farming := range farmings {
-		if farming.MaxAPR != nil {
-			response[farming.Hash] = *farming.MaxAPR
+		maxAPR := selectAPRStat(stat, farming.APRP25, farming.APRMedian, farming.APRP75, farming.APRP90, farming.MaxAPR, farming.RealisticMaxAPR)
+		maxAPY := selectAPRStat(stat, farming.APYP25, farming.APYMedian, farming.APYP75, farming.APYP90, farming.MaxAPY, farming.RealisticMaxAPY)
+		response[farming.Hash] = valueOrZero(selectMetric(metric, maxAPR, maxAPY))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GET /api/pools/:address?network=Polygon
+func (h *Handler) GetPool(c *gin.Context) {
+	networkName := c.DefaultQuery("network", "Polygon")
+
+	pool, ok := h.findPool(c, networkName)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"address":           pool.Address,
+		"title":             pool.Title,
+		"network":           networkName,
+		"tvl":               pool.TVL,
+		"tvl_usd":           pool.TVLUSD,
+		"fees":              pool.Fees,
+		"fees_usd":          pool.FeesUSD,
+		"apr":               pool.LastAPR,
+		"apr_7d":            pool.APR7d,
+		"apr_30d":           pool.APR30d,
+		"farming_apr":       pool.FarmingAPR,
+		"total_apr":         pool.TotalAPR,
+		"apy":               pool.APY,
+		"farming_apy":       pool.FarmingAPY,
+		"total_apy":         pool.TotalAPY,
+		"max_apy":           pool.MaxAPY,
+		"max_apr":           pool.MaxAPR,
+		"realistic_max_apr": pool.RealisticMaxAPR,
+		"max_apr_position":  maxAPRPositionResponse(pool.MaxAPRPositionID, pool.MaxAPRTickLower, pool.MaxAPRTickUpper, pool.MaxAPRPriceLower, pool.MaxAPRPriceUpper),
+		"apr_distribution": gin.H{
+			"p25":    pool.APRP25,
+			"median": pool.APRMedian,
+			"p75":    pool.APRP75,
+			"p90":    pool.APRP90,
+			"max":    pool.MaxAPR,
+		},
+	})
+}
+
+// GET /api/pools/:address/range-apr?network=Polygon
+func (h *Handler) GetPoolRangeAPR(c *gin.Context) {
+	networkName := c.DefaultQuery("network", "Polygon")
+
+	pool, ok := h.findPool(c, networkName)
+	if !ok {
+		return
+	}
+
+	// Ranges of the latest run that computed them
+	var rangeAPRs []models.PoolRangeAPR
+	latestRun := h.db.Model(&models.PoolRangeAPR{}).Select("MAX(run_at)").Where("pool_id = ?", pool.ID)
+	result := h.db.Where("pool_id = ? AND run_at = (?)", pool.ID, latestRun).Order("id asc").Find(&rangeAPRs)
+	if result.Error != nil {
+		logger.Logger.Error("Failed to fetch pool range APR", zap.Error(result.Error))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pool range APR"})
+		return
+	}
+
+	var runAt *int64
+	if len(rangeAPRs) > 0 {
+		timestamp := rangeAPRs[0].RunAt.Unix()
+		runAt = &timestamp
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"address":      pool.Address,
+		"network":      networkName,
+		"current_tick": pool.Tick,
+		"run_at":       runAt,
+		"ranges":       rangeAPRs,
+	})
+}
+
+// GET /api/pools/total-apr?network=Polygon&metric=apr
+func (h *Handler) GetPoolsTotalAPR(c *gin.Context) {
+	networkName := c.DefaultQuery("network", "Polygon")
+
+	metric, ok := parseMetric(c)
+	if !ok {
+		return
+	}
+
+	var pools []models.Pool
+	result := h.db.Preload("Network").Joins("JOIN networks ON pools.network_id = networks.id").Where("networks.title = ?", networkName).Find(&pools)
+	if result.Error != nil {
+		logger.Logger.Error("Failed to fetch pools", zap.Error(result.Error))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pools"})
+		return
+	}
+
+	response := make(map[string]interface{})
+	for _, pool := range pools {
+		response[pool.Address] = gin.H{
+			"fee_" + metric:     valueOrZero(selectMetric(metric, pool.LastAPR, pool.APY)),
+			"farming_" + metric: valueOrZero(selectMetric(metric, pool.FarmingAPR, pool.FarmingAPY)),
+			"total_" + metric:   valueOrZero(selectMetric(metric, pool.TotalAPR, pool.TotalAPY)),
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GET /api/pools/tvl?network=Polygon&currency=native
+func (h *Handler) GetPoolsTVL(c *gin.Context) {
+	networkName := c.DefaultQuery("network", "Polygon")
+	currency := c.DefaultQuery("currency", "native")
+
+	if currency != "native" && currency != "usd" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency, expected one of usd, native"})
+		return
+	}
+
+	var pools []models.Pool
+	result := h.db.Preload("Network").Joins("JOIN networks ON pools.network_id = networks.id").Where("networks.title = ?", networkName).Find(&pools)
+	if result.Error != nil {
+		logger.Logger.Error("Failed to fetch pools", zap.Error(result.Error))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pools"})
+		return
+	}
+
+	response := make(map[string]interface{})
+	for _, pool := range pools {
+		tvl := pool.TVL
+		if currency == "usd" {
+			tvl = pool.TVLUSD
+		}
+
+		if tvl != nil {
+			response[pool.Address] = *tvl
 		} else {
-			response[farming.Hash] = 0.0
+			response[pool.Address] = 0.0
 		}
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// GET /api/eternal-farmings/tvl?network=Polygon
+// GET /api/eternal-farmings/tvl?network=Polygon&currency=native&status=active
 func (h *Handler) GetFarmingsTVL(c *gin.Context) {
 	networkName := c.DefaultQuery("network", "Polygon")
+	currency := c.DefaultQuery("currency", "native")
 
-	var farmings []models.Farming
-	result := h.db.Preload("Network").Joins("JOIN networks ON farmings.network_id = networks.id").Where("networks.title = ?", networkName).Find(&farmings)
-	if result.Error != nil {
-		logger.Logger.Error("Failed to fetch eternal farmings", zap.Error(result.Error))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch eternal farmings"})
+	if currency != "native" && currency != "usd" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency, expected one of usd, native"})
+		return
+	}
+
+	farmings, ok := h.findFarmings(c, networkName)
+	if !ok {
 		return
 	}
 
 	response := make(map[string]interface{})
 	for _, farming := range farmings {
-		if farming.TVL != nil {
-			response[farming.Hash] = *farming.TVL
+		tvl := farming.TVL
+		if currency == "usd" {
+			tvl = farming.TVLUSD
+		}
+
+		if tvl != nil {
+			response[farming.Hash] = *tvl
 		} else {
 			response[farming.Hash] = 0.0
 		}
@@ -139,3 +331,229 @@ func (h *Handler) GetFarmingsTVL(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// GET /api/eternal-farmings/rewards-remaining?network=Polygon&status=active
+func (h *Handler) GetFarmingsRewardsRemaining(c *gin.Context) {
+	networkName := c.DefaultQuery("network", "Polygon")
+
+	farmings, ok := h.findFarmings(c, networkName)
+	if !ok {
+		return
+	}
+
+	response := make(map[string]interface{})
+	for _, farming := range farmings {
+		response[farming.Hash] = gin.H{
+			"reward_seconds_remaining":       farming.RewardSecondsRemaining,
+			"reward_days_remaining":          secondsToDays(farming.RewardSecondsRemaining),
+			"bonus_reward_seconds_remaining": farming.BonusRewardSecondsRemaining,
+			"bonus_reward_days_remaining":    secondsToDays(farming.BonusRewardSecondsRemaining),
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// parseAPRStat reads the APR distribution statistic requested with ?stat=, writing a 400 response if it is unknown
+func parseAPRStat(c *gin.Context) (string, bool) {
+	stat := c.DefaultQuery("stat", "max")
+	switch stat {
+	case "p25", "median", "p75", "p90", "max", "realistic":
+		return stat, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stat, expected one of p25, median, p75, p90, max, realistic"})
+		return "", false
+	}
+}
+
+// selectAPRStat returns the value of the requested APR distribution statistic
+func selectAPRStat(stat string, p25, median, p75, p90, max, realisticMax *float64) *float64 {
+	switch stat {
+	case "realistic":
+		return realisticMax
+	case "p25":
+		return p25
+	case "median":
+		return median
+	case "p75":
+		return p75
+	case "p90":
+		return p90
+	default:
+		return max
+	}
+}
+
+// parseMetric reads whether simple APR or compounded APY is requested with ?metric=, writing a 400 response if it is unknown
+func parseMetric(c *gin.Context) (string, bool) {
+	metric := c.DefaultQuery("metric", "apr")
+	if metric != "apr" && metric != "apy" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid metric, expected one of apr, apy"})
+		return "", false
+	}
+	return metric, true
+}
+
+// parseMaxAPRParams reads the stat and metric of the max-apr endpoints
+func parseMaxAPRParams(c *gin.Context) (string, string, bool) {
+	stat, ok := parseAPRStat(c)
+	if !ok {
+		return "", "", false
+	}
+
+	metric, ok := parseMetric(c)
+	if !ok {
+		return "", "", false
+	}
+
+	return stat, metric, true
+}
+
+// selectMetric returns the APY when it is requested, the APR otherwise
+func selectMetric(metric string, apr, apy *float64) *float64 {
+	if metric == "apy" {
+		return apy
+	}
+	return apr
+}
+
+// maxAPRPositionResponse describes the position and range that produced the max APR, nil if there is none
+func maxAPRPositionResponse(positionID *string, tickLower, tickUpper *int, priceLower, priceUpper *float64) gin.H {
+	if positionID == nil {
+		return nil
+	}
+
+	return gin.H{
+		"id":          *positionID,
+		"tick_lower":  tickLower,
+		"tick_upper":  tickUpper,
+		"price_lower": priceLower,
+		"price_upper": priceUpper,
+	}
+}
+
+func valueOrZero(value *float64) float64 {
+	if value == nil {
+		return 0.0
+	}
+	return *value
+}
+
+func secondsToDays(seconds *float64) *float64 {
+	if seconds == nil {
+		return nil
+	}
+
+	days := *seconds / 86400
+	return &days
+}
+
+// GET /api/eternal-farmings/:hash?network=Polygon
+func (h *Handler) GetFarming(c *gin.Context) {
+	networkName := c.DefaultQuery("network", "Polygon")
+
+	farming, ok := h.findFarming(c, networkName, "Rewards", "Pool")
+	if !ok {
+		return
+	}
+
+	rewards := farming.Rewards
+	if rewards == nil {
+		rewards = []models.FarmingReward{}
+	}
+
+	var poolAddress *string
+	if farming.Pool != nil {
+		poolAddress = &farming.Pool.Address
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"hash":                           farming.Hash,
+		"network":                        networkName,
+		"pool":                           poolAddress,
+		"status":                         farming.Status,
+		"start_time":                     farming.StartTime,
+		"end_time":                       farming.EndTime,
+		"tvl":                            farming.TVL,
+		"tvl_usd":                        farming.TVLUSD,
+		"apr":                            farming.LastAPR,
+		"apy":                            farming.APY,
+		"marginal_apr":                   farming.MarginalAPR,
+		"max_apr":                        farming.MaxAPR,
+		"max_apy":                        farming.MaxAPY,
+		"realistic_max_apr":              farming.RealisticMaxAPR,
+		"max_apr_position":               maxAPRPositionResponse(farming.MaxAPRPositionID, farming.MaxAPRTickLower, farming.MaxAPRTickUpper, farming.MaxAPRPriceLower, farming.MaxAPRPriceUpper),
+		"reward_seconds_remaining":       farming.RewardSecondsRemaining,
+		"bonus_reward_seconds_remaining": farming.BonusRewardSecondsRemaining,
+		"rewards":                        rewards,
+	})
+}
+
+// findPool loads the pool of the :address path parameter in a network, writing a 404 or 500 response
+// if it can't be loaded. Subgraph addresses are stored lowercase, so checksummed addresses match too.
+func (h *Handler) findPool(c *gin.Context, networkName string) (models.Pool, bool) {
+	address := strings.ToLower(c.Param("address"))
+
+	var pool models.Pool
+	result := h.db.Joins("JOIN networks ON pools.network_id = networks.id").Where("networks.title = ? AND pools.address = ?", networkName, address).First(&pool)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pool not found"})
+			return models.Pool{}, false
+		}
+		logger.Logger.Error("Failed to fetch pool", zap.Error(result.Error))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pool"})
+		return models.Pool{}, false
+	}
+
+	return pool, true
+}
+
+// findFarming loads the eternal farming of the :hash path parameter in a network with the given associations,
+// writing a 404 or 500 response if it can't be loaded. Subgraph hashes are stored lowercase, so mixed-case hashes match too.
+func (h *Handler) findFarming(c *gin.Context, networkName string, preloads ...string) (models.Farming, bool) {
+	hash := strings.ToLower(c.Param("hash"))
+
+	query := h.db
+	for _, preload := range preloads {
+		query = query.Preload(preload)
+	}
+
+	var farming models.Farming
+	result := query.Joins("JOIN networks ON farmings.network_id = networks.id").Where("networks.title = ? AND farmings.hash = ?", networkName, hash).First(&farming)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Eternal farming not found"})
+			return models.Farming{}, false
+		}
+		logger.Logger.Error("Failed to fetch eternal farming", zap.Error(result.Error))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch eternal farming"})
+		return models.Farming{}, false
+	}
+
+	return farming, true
+}
+
+// findFarmings returns the eternal farmings of a network filtered by the status query parameter
+// (active by default, or all). It writes the error response itself when it returns false.
+func (h *Handler) findFarmings(c *gin.Context, networkName string) ([]models.Farming, bool) {
+	status := c.DefaultQuery("status", "active")
+	if status != "active" && status != "all" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status, expected one of active, all"})
+		return nil, false
+	}
+
+	query := h.db.Preload("Network").Joins("JOIN networks ON farmings.network_id = networks.id").Where("networks.title = ?", networkName)
+	if status == "active" {
+		query = query.Where("farmings.status = ?", models.FarmingStatusActive)
+	}
+
+	var farmings []models.Farming
+	if result := query.Find(&farmings); result.Error != nil {
+		logger.Logger.Error("Failed to fetch eternal farmings", zap.Error(result.Error))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch eternal farmings"})
+		return nil, false
+	}
+
+	return farmings, true
+}
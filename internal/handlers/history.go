@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"algebra-apr-backend/internal/logger"
+	"algebra-apr-backend/internal/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const defaultHistoryWindow = 30 * 24 * time.Hour
+
+var historyIntervals = map[string]time.Duration{
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+	"1w": 7 * 24 * time.Hour,
+}
+
+// HistoryStats aggregates the values of a single metric inside a bucket
+type HistoryStats struct {
+	Avg  float64 `json:"avg"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Last float64 `json:"last"`
+
+	count int
+}
+
+// HistoryPoint is a single bucket of the history response
+type HistoryPoint struct {
+	Timestamp int64         `json:"timestamp"`
+	APR       *HistoryStats `json:"apr"`
+	MaxAPR    *HistoryStats `json:"max_apr"`
+	TVL       *HistoryStats `json:"tvl"`
+}
+
+type historySample struct {
+	RunAt  time.Time
+	APR    *float64
+	MaxAPR *float64
+	TVL    *float64
+}
+
+type historyParams struct {
+	from     time.Time
+	to       time.Time
+	interval string
+}
+
+// GET /api/pools/:address/history?network=Polygon&from=1700000000&to=1700086400&interval=1d
+func (h *Handler) GetPoolHistory(c *gin.Context) {
+	networkName := c.DefaultQuery("network", "Polygon")
+
+	params, err := parseHistoryParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pool, ok := h.findPool(c, networkName)
+	if !ok {
+		return
+	}
+
+	var snapshots []models.PoolSnapshot
+	result := h.db.Where("pool_id = ? AND run_at >= ? AND run_at < ?", pool.ID, params.from, params.to).Order("run_at asc").Find(&snapshots)
+	if result.Error != nil {
+		logger.Logger.Error("Failed to fetch pool snapshots", zap.Error(result.Error))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pool history"})
+		return
+	}
+
+	samples := make([]historySample, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		samples = append(samples, historySample{RunAt: snapshot.RunAt, APR: snapshot.APR, MaxAPR: snapshot.MaxAPR, TVL: snapshot.TVL})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"address":  pool.Address,
+		"network":  networkName,
+		"interval": params.interval,
+		"from":     params.from.Unix(),
+		"to":       params.to.Unix(),
+		"points":   bucketHistory(samples, historyIntervals[params.interval]),
+	})
+}
+
+// GET /api/eternal-farmings/:hash/history?network=Polygon&from=1700000000&to=1700086400&interval=1d
+func (h *Handler) GetFarmingHistory(c *gin.Context) {
+	networkName := c.DefaultQuery("network", "Polygon")
+
+	params, err := parseHistoryParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	farming, ok := h.findFarming(c, networkName)
+	if !ok {
+		return
+	}
+
+	var snapshots []models.FarmingSnapshot
+	result := h.db.Where("farming_id = ? AND run_at >= ? AND run_at < ?", farming.ID, params.from, params.to).Order("run_at asc").Find(&snapshots)
+	if result.Error != nil {
+		logger.Logger.Error("Failed to fetch farming snapshots", zap.Error(result.Error))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch eternal farming history"})
+		return
+	}
+
+	samples := make([]historySample, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		samples = append(samples, historySample{RunAt: snapshot.RunAt, APR: snapshot.APR, MaxAPR: snapshot.MaxAPR, TVL: snapshot.TVL})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"hash":     farming.Hash,
+		"network":  networkName,
+		"interval": params.interval,
+		"from":     params.from.Unix(),
+		"to":       params.to.Unix(),
+		"points":   bucketHistory(samples, historyIntervals[params.interval]),
+	})
+}
+
+// parseHistoryParams reads the from/to unix timestamps and the bucket interval from the query
+func parseHistoryParams(c *gin.Context) (historyParams, error) {
+	params := historyParams{
+		to:       time.Now().UTC(),
+		interval: c.DefaultQuery("interval", "1d"),
+	}
+
+	if _, ok := historyIntervals[params.interval]; !ok {
+		return params, fmt.Errorf("invalid interval %q, expected one of 1h, 1d, 1w", params.interval)
+	}
+
+	if to := c.Query("to"); to != "" {
+		ts, err := strconv.ParseInt(to, 10, 64)
+		if err != nil {
+			return params, fmt.Errorf("invalid to timestamp %q", to)
+		}
+		params.to = time.Unix(ts, 0).UTC()
+	}
+
+	params.from = params.to.Add(-defaultHistoryWindow)
+	if from := c.Query("from"); from != "" {
+		ts, err := strconv.ParseInt(from, 10, 64)
+		if err != nil {
+			return params, fmt.Errorf("invalid from timestamp %q", from)
+		}
+		params.from = time.Unix(ts, 0).UTC()
+	}
+
+	if !params.from.Before(params.to) {
+		return params, fmt.Errorf("from must be before to")
+	}
+
+	return params, nil
+}
+
+// bucketHistory groups samples ordered by run time into buckets of the given interval.
+// Weekly buckets start on Monday 00:00 UTC.
+func bucketHistory(samples []historySample, interval time.Duration) []HistoryPoint {
+	points := make([]HistoryPoint, 0)
+
+	for _, sample := range samples {
+		bucket := sample.RunAt.UTC().Truncate(interval).Unix()
+
+		if len(points) == 0 || points[len(points)-1].Timestamp != bucket {
+			points = append(points, HistoryPoint{Timestamp: bucket})
+		}
+
+		point := &points[len(points)-1]
+		point.APR = addHistoryValue(point.APR, sample.APR)
+		point.MaxAPR = addHistoryValue(point.MaxAPR, sample.MaxAPR)
+		point.TVL = addHistoryValue(point.TVL, sample.TVL)
+	}
+
+	return points
+}
+
+func addHistoryValue(stats *HistoryStats, value *float64) *HistoryStats {
+	if value == nil {
+		return stats
+	}
+
+	if stats == nil {
+		return &HistoryStats{Avg: *value, Min: *value, Max: *value, Last: *value, count: 1}
+	}
+
+	stats.Avg = (stats.Avg*float64(stats.count) + *value) / float64(stats.count+1)
+	stats.count++
+	if *value < stats.Min {
+		stats.Min = *value
+	}
+	if *value > stats.Max {
+		stats.Max = *value
+	}
+	stats.Last = *value
+
+	return stats
+}
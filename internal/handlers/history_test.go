@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func historyFloat(value float64) *float64 {
+	return &value
+}
+
+func TestBucketHistory(t *testing.T) {
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	if monday.Weekday() != time.Monday {
+		t.Fatalf("%s is not a Monday", monday)
+	}
+
+	samples := []historySample{
+		{RunAt: monday.Add(2*24*time.Hour + 3*time.Hour), APR: historyFloat(1), MaxAPR: historyFloat(10)},
+		{RunAt: monday.Add(4 * 24 * time.Hour), APR: historyFloat(3)},
+		{RunAt: monday.Add(6*24*time.Hour + 23*time.Hour), APR: historyFloat(2), MaxAPR: historyFloat(5)},
+		{RunAt: monday.Add(7 * 24 * time.Hour), APR: historyFloat(7)},
+	}
+
+	points := bucketHistory(samples, historyIntervals["1w"])
+	if len(points) != 2 {
+		t.Fatalf("bucketHistory() returned %d points, expected 2", len(points))
+	}
+
+	tests := []struct {
+		name     string
+		stats    *HistoryStats
+		expected *HistoryStats
+	}{
+		{name: "apr of the first week", stats: points[0].APR, expected: &HistoryStats{Avg: 2, Min: 1, Max: 3, Last: 2}},
+		{name: "max apr skips missing values", stats: points[0].MaxAPR, expected: &HistoryStats{Avg: 7.5, Min: 5, Max: 10, Last: 5}},
+		{name: "tvl without values", stats: points[0].TVL, expected: nil},
+		{name: "apr of the second week", stats: points[1].APR, expected: &HistoryStats{Avg: 7, Min: 7, Max: 7, Last: 7}},
+		{name: "max apr of the second week", stats: points[1].MaxAPR, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.expected == nil {
+				if tt.stats != nil {
+					t.Errorf("stats = %+v, expected nil", *tt.stats)
+				}
+				return
+			}
+			if tt.stats == nil {
+				t.Fatalf("stats = nil, expected %+v", *tt.expected)
+			}
+			if tt.stats.Avg != tt.expected.Avg || tt.stats.Min != tt.expected.Min || tt.stats.Max != tt.expected.Max || tt.stats.Last != tt.expected.Last {
+				t.Errorf("stats = %+v, expected %+v", *tt.stats, *tt.expected)
+			}
+		})
+	}
+
+	if points[0].Timestamp != monday.Unix() || points[1].Timestamp != monday.Add(7*24*time.Hour).Unix() {
+		t.Errorf("weekly buckets start at %d and %d, expected Mondays %d and %d", points[0].Timestamp, points[1].Timestamp, monday.Unix(), monday.Add(7*24*time.Hour).Unix())
+	}
+}
+
+func TestParseHistoryParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name             string
+		query            string
+		expectError      bool
+		expectedFrom     int64
+		expectedTo       int64
+		expectedInterval string
+	}{
+		{name: "explicit range", query: "from=1760000000&to=1760086400&interval=1h", expectedFrom: 1760000000, expectedTo: 1760086400, expectedInterval: "1h"},
+		{name: "default window and interval", query: "to=1760086400", expectedFrom: 1760086400 - 30*86400, expectedTo: 1760086400, expectedInterval: "1d"},
+		{name: "from equal to to", query: "from=1760086400&to=1760086400", expectError: true},
+		{name: "from after to", query: "from=1760090000&to=1760086400", expectError: true},
+		{name: "unknown interval", query: "interval=1m", expectError: true},
+		{name: "invalid timestamp", query: "from=yesterday", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/pools/0xpool/history?"+tt.query, nil)
+
+			params, err := parseHistoryParams(c)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("parseHistoryParams(%q) = %+v, expected an error", tt.query, params)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseHistoryParams(%q) returned error: %v", tt.query, err)
+			}
+			if params.from.Unix() != tt.expectedFrom || params.to.Unix() != tt.expectedTo || params.interval != tt.expectedInterval {
+				t.Errorf("parseHistoryParams(%q) = %+v, expected from %d, to %d and interval %s", tt.query, params, tt.expectedFrom, tt.expectedTo, tt.expectedInterval)
+			}
+		})
+	}
+}
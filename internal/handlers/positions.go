@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"algebra-apr-backend/internal/logger"
+	"algebra-apr-backend/internal/models"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// GET /api/positions/:id?network=Polygon
+func (h *Handler) GetPosition(c *gin.Context) {
+	networkName := c.DefaultQuery("network", "Polygon")
+	positionID := c.Param("id")
+
+	var position models.Position
+	result := h.db.Joins("JOIN networks ON positions.network_id = networks.id").Where("networks.title = ? AND positions.position_id = ?", networkName, positionID).First(&position)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Position not found"})
+			return
+		}
+		logger.Logger.Error("Failed to fetch position", zap.Error(result.Error))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch position"})
+		return
+	}
+
+	c.JSON(http.StatusOK, position)
+}
+
+// GET /api/wallets/:owner/positions?network=Polygon
+func (h *Handler) GetWalletPositions(c *gin.Context) {
+	networkName := c.DefaultQuery("network", "Polygon")
+	owner := strings.ToLower(c.Param("owner"))
+
+	var positions []models.Position
+	result := h.db.Joins("JOIN networks ON positions.network_id = networks.id").Where("networks.title = ? AND positions.owner = ?", networkName, owner).Order("positions.id asc").Find(&positions)
+	if result.Error != nil {
+		logger.Logger.Error("Failed to fetch wallet positions", zap.Error(result.Error))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet positions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"owner":     owner,
+		"network":   networkName,
+		"positions": positions,
+	})
+}
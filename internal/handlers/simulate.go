@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"algebra-apr-backend/internal/logger"
+	"algebra-apr-backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// POST /api/simulate
+func (h *Handler) SimulateAPR(c *gin.Context) {
+	var req services.SimulationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Currency == "" {
+		req.Currency = "native"
+	}
+	if req.Currency != "native" && req.Currency != "usd" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency, expected one of usd, native"})
+		return
+	}
+
+	simulation, err := h.aprService.Simulate(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPoolNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pool not found"})
+		case errors.Is(err, services.ErrPoolStateUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Pool data is not available yet"})
+		case errors.Is(err, services.ErrInvalidSimulation):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			logger.Logger.Error("Failed to simulate APR", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to simulate APR"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, simulation)
+}
@@ -36,5 +36,259 @@ func GetMigrations() []*gormigrate.Migration {
 				return tx.Migrator().DropTable(&models.Farming{})
 			},
 		},
+		{
+			ID: "202610160001_add_pool_tvl_and_fees",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Pool{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return dropColumns(tx, &models.Pool{}, "TVL", "Fees")
+			},
+		},
+		{
+			ID: "202610160002_create_pool_snapshots_table",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.PoolSnapshot{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.PoolSnapshot{})
+			},
+		},
+		{
+			ID: "202610160003_create_farming_snapshots_table",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.FarmingSnapshot{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.FarmingSnapshot{})
+			},
+		},
+		{
+			ID: "202610160004_add_pool_windowed_fee_apr",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Pool{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return dropColumns(tx, &models.Pool{}, "APR7d", "APR30d")
+			},
+		},
+		{
+			ID: "202610160005_add_usd_values",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Network{}, &models.Pool{}, &models.Farming{}, &models.PoolSnapshot{}, &models.FarmingSnapshot{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := dropColumns(tx, &models.Network{}, "NativePriceUSD"); err != nil {
+					return err
+				}
+				if err := dropColumns(tx, &models.Pool{}, "TVLUSD", "FeesUSD"); err != nil {
+					return err
+				}
+				if err := dropColumns(tx, &models.Farming{}, "TVLUSD"); err != nil {
+					return err
+				}
+				if err := dropColumns(tx, &models.PoolSnapshot{}, "TVLUSD", "FeesUSD"); err != nil {
+					return err
+				}
+				return dropColumns(tx, &models.FarmingSnapshot{}, "TVLUSD")
+			},
+		},
+		{
+			ID: "202610160006_create_positions_table",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Position{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.Position{})
+			},
+		},
+		{
+			ID: "202610160007_add_pool_and_farming_state",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Pool{}, &models.Farming{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := dropColumns(tx, &models.Pool{}, "Tick", "Liquidity", "Token0Decimals", "Token1Decimals", "Token0DerivedMatic", "Token1DerivedMatic"); err != nil {
+					return err
+				}
+				return dropColumns(tx, &models.Farming{}, "RewardRate", "ActiveLiquidity")
+			},
+		},
+		{
+			ID: "202610160008_add_farming_reward_reserves",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Farming{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return dropColumns(tx, &models.Farming{}, "RewardSecondsRemaining", "BonusRewardSecondsRemaining")
+			},
+		},
+		{
+			ID: "202610160009_add_farming_status",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Farming{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return dropColumns(tx, &models.Farming{}, "Status", "StartTime", "EndTime")
+			},
+		},
+		{
+			ID: "202610160010_create_farming_rewards_table",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.FarmingReward{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.FarmingReward{})
+			},
+		},
+		{
+			ID: "202610160011_link_farmings_to_pools",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&models.Pool{}, &models.Farming{}); err != nil {
+					return err
+				}
+				if tx.Migrator().HasConstraint(&models.Farming{}, "Pool") {
+					return nil
+				}
+				return tx.Migrator().CreateConstraint(&models.Farming{}, "Pool")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if tx.Migrator().HasConstraint(&models.Farming{}, "Pool") {
+					if err := tx.Migrator().DropConstraint(&models.Farming{}, "Pool"); err != nil {
+						return err
+					}
+				}
+				if err := dropColumns(tx, &models.Pool{}, "FarmingAPR", "TotalAPR"); err != nil {
+					return err
+				}
+				return dropColumns(tx, &models.Farming{}, "PoolID")
+			},
+		},
+		{
+			ID: "202610160012_add_apr_distribution",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Pool{}, &models.Farming{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := dropColumns(tx, &models.Pool{}, "APRP25", "APRMedian", "APRP75", "APRP90"); err != nil {
+					return err
+				}
+				return dropColumns(tx, &models.Farming{}, "APRP25", "APRMedian", "APRP75", "APRP90")
+			},
+		},
+		{
+			ID: "202610160013_add_realistic_max_apr",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Pool{}, &models.Farming{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := dropColumns(tx, &models.Pool{}, "RealisticMaxAPR"); err != nil {
+					return err
+				}
+				return dropColumns(tx, &models.Farming{}, "RealisticMaxAPR")
+			},
+		},
+		{
+			ID: "202610160014_add_max_apr_position",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Pool{}, &models.Farming{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				fields := []string{"MaxAPRPositionID", "MaxAPRTickLower", "MaxAPRTickUpper", "MaxAPRPriceLower", "MaxAPRPriceUpper"}
+				if err := dropColumns(tx, &models.Pool{}, fields...); err != nil {
+					return err
+				}
+				return dropColumns(tx, &models.Farming{}, fields...)
+			},
+		},
+		{
+			ID: "202610160015_create_pool_range_aprs_table",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.PoolRangeAPR{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.PoolRangeAPR{})
+			},
+		},
+		{
+			ID: "202610160016_add_farming_marginal_apr",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Farming{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return dropColumns(tx, &models.Farming{}, "MarginalAPR")
+			},
+		},
+		{
+			ID: "202610160017_add_apy",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Pool{}, &models.Farming{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := dropColumns(tx, &models.Pool{}, "APY", "APY7d", "APY30d", "MaxAPY", "FarmingAPY", "TotalAPY"); err != nil {
+					return err
+				}
+				return dropColumns(tx, &models.Farming{}, "APY", "MaxAPY")
+			},
+		},
+		{
+			ID: "202610160018_add_block_numbers",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Network{}, &models.PoolSnapshot{}, &models.FarmingSnapshot{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := dropColumns(tx, &models.Network{}, "AnalyticsBlockNumber", "FarmingBlockNumber"); err != nil {
+					return err
+				}
+				if err := dropColumns(tx, &models.PoolSnapshot{}, "BlockNumber"); err != nil {
+					return err
+				}
+				return dropColumns(tx, &models.FarmingSnapshot{}, "BlockNumber")
+			},
+		},
+		{
+			ID: "202610160019_add_pool_sqrt_price",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Pool{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return dropColumns(tx, &models.Pool{}, "SqrtPrice")
+			},
+		},
+		{
+			ID: "202610160020_add_stat_apy",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Pool{}, &models.Farming{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				fields := []string{"APYP25", "APYMedian", "APYP75", "APYP90", "RealisticMaxAPY"}
+				if err := dropColumns(tx, &models.Pool{}, fields...); err != nil {
+					return err
+				}
+				return dropColumns(tx, &models.Farming{}, append(fields, "MarginalAPY")...)
+			},
+		},
+		{
+			ID: "202610160021_add_pool_tick_spacing",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Pool{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return dropColumns(tx, &models.Pool{}, "TickSpacing")
+			},
+		},
+	}
+}
+
+// dropColumns removes columns added by a migration, skipping ones that are already gone
+func dropColumns(tx *gorm.DB, model interface{}, fields ...string) error {
+	for _, field := range fields {
+		if !tx.Migrator().HasColumn(model, field) {
+			continue
+		}
+		if err := tx.Migrator().DropColumn(model, field); err != nil {
+			return err
+		}
 	}
+	return nil
 }
@@ -12,30 +12,214 @@ type BaseModel struct {
 
 type Network struct {
 	BaseModel
-	Title                string `json:"title" gorm:"size:255;not null"`
-	AnalyticsSubgraphURL string `json:"analytics_subgraph_url" gorm:"not null"`
-	FarmingSubgraphURL   string `json:"farming_subgraph_url" gorm:"not null"`
-	APIKey               string `json:"api_key" gorm:"size:255"`
+	Title                string   `json:"title" gorm:"size:255;not null"`
+	AnalyticsSubgraphURL string   `json:"analytics_subgraph_url" gorm:"not null"`
+	FarmingSubgraphURL   string   `json:"farming_subgraph_url" gorm:"not null"`
+	APIKey               string   `json:"api_key" gorm:"size:255"`
+	NativePriceUSD       *float64 `json:"native_price_usd"`
+
+	// Subgraph blocks the latest APR update run was pinned to
+	AnalyticsBlockNumber *int64 `json:"analytics_block_number"`
+	FarmingBlockNumber   *int64 `json:"farming_block_number"`
 }
 
 type Pool struct {
 	BaseModel
 	Title     string   `json:"title" gorm:"size:256;not null"`
 	Address   string   `json:"address" gorm:"size:42;not null"`
+	TVL       *float64 `json:"tvl"`
+	TVLUSD    *float64 `json:"tvl_usd" gorm:"column:tvl_usd"`
+	Fees      *float64 `json:"fees"`
+	FeesUSD   *float64 `json:"fees_usd" gorm:"column:fees_usd"`
 	LastAPR   *float64 `json:"last_apr"`
+	APR7d     *float64 `json:"apr_7d" gorm:"column:apr_7d"`
+	APR30d    *float64 `json:"apr_30d" gorm:"column:apr_30d"`
 	MaxAPR    *float64 `json:"max_apr"`
 	NetworkID uint     `json:"network_id"`
 	Network   Network  `json:"network" gorm:"foreignKey:NetworkID"`
+
+	// Liquidity-weighted distribution of the position fee APRs, MaxAPR is its maximum
+	APRP25    *float64 `json:"apr_p25" gorm:"column:apr_p25"`
+	APRMedian *float64 `json:"apr_median" gorm:"column:apr_median"`
+	APRP75    *float64 `json:"apr_p75" gorm:"column:apr_p75"`
+	APRP90    *float64 `json:"apr_p90" gorm:"column:apr_p90"`
+
+	// Max APR of the positions passing the network's minimum value, range width and liquidity share
+	RealisticMaxAPR *float64 `json:"realistic_max_apr"`
+
+	// Position that produced MaxAPR and its range, empty when no position earns an APR
+	MaxAPRPositionID *string  `json:"max_apr_position_id" gorm:"size:80"`
+	MaxAPRTickLower  *int     `json:"max_apr_tick_lower"`
+	MaxAPRTickUpper  *int     `json:"max_apr_tick_upper"`
+	MaxAPRPriceLower *float64 `json:"max_apr_price_lower"` // Token0 price in token1
+	MaxAPRPriceUpper *float64 `json:"max_apr_price_upper"`
+
+	// Pool state of the latest run, used to simulate new deposits
+	Tick               *int     `json:"tick"`
+	TickSpacing        int      `json:"tick_spacing"`
+	SqrtPrice          string   `json:"sqrt_price" gorm:"size:80"` // Q64.96
+	Liquidity          string   `json:"liquidity" gorm:"size:80"`
+	Token0Decimals     int      `json:"token0_decimals"`
+	Token1Decimals     int      `json:"token1_decimals"`
+	Token0DerivedMatic *float64 `json:"token0_derived_matic"`
+	Token1DerivedMatic *float64 `json:"token1_derived_matic"`
+
+	// Fee APR plus the APR of all active farmings of the pool
+	FarmingAPR *float64 `json:"farming_apr"`
+	TotalAPR   *float64 `json:"total_apr"`
+
+	// APR values compounded at the configured frequency
+	APY             *float64 `json:"apy"`
+	APY7d           *float64 `json:"apy_7d" gorm:"column:apy_7d"`
+	APY30d          *float64 `json:"apy_30d" gorm:"column:apy_30d"`
+	MaxAPY          *float64 `json:"max_apy"`
+	FarmingAPY      *float64 `json:"farming_apy"`
+	TotalAPY        *float64 `json:"total_apy"`
+	APYP25          *float64 `json:"apy_p25" gorm:"column:apy_p25"`
+	APYMedian       *float64 `json:"apy_median" gorm:"column:apy_median"`
+	APYP75          *float64 `json:"apy_p75" gorm:"column:apy_p75"`
+	APYP90          *float64 `json:"apy_p90" gorm:"column:apy_p90"`
+	RealisticMaxAPY *float64 `json:"realistic_max_apy"`
+
+	Farmings []Farming `json:"farmings,omitempty" gorm:"foreignKey:PoolID"`
 }
 
+// Eternal farming statuses
+const (
+	FarmingStatusActive      = "active"
+	FarmingStatusPending     = "pending"
+	FarmingStatusEnded       = "ended"
+	FarmingStatusDeactivated = "deactivated"
+)
+
 type Farming struct {
 	BaseModel
 	Hash      string   `json:"hash" gorm:"size:66;uniqueIndex;not null"`
+	Status    string   `json:"status" gorm:"size:16;not null;default:active;index"`
+	StartTime *int64   `json:"start_time"`
+	EndTime   *int64   `json:"end_time"`
 	TVL       *float64 `json:"tvl"`
+	TVLUSD    *float64 `json:"tvl_usd" gorm:"column:tvl_usd"`
 	LastAPR   *float64 `json:"last_apr"`
 	MaxAPR    *float64 `json:"max_apr"`
 	NetworkID uint     `json:"network_id"`
 	Network   Network  `json:"network" gorm:"foreignKey:NetworkID"`
+
+	// Liquidity-weighted distribution of the deposited position farming APRs, MaxAPR is its maximum
+	APRP25    *float64 `json:"apr_p25" gorm:"column:apr_p25"`
+	APRMedian *float64 `json:"apr_median" gorm:"column:apr_median"`
+	APRP75    *float64 `json:"apr_p75" gorm:"column:apr_p75"`
+	APRP90    *float64 `json:"apr_p90" gorm:"column:apr_p90"`
+
+	// Max APR of the positions passing the network's minimum value, range width and liquidity share
+	RealisticMaxAPR *float64 `json:"realistic_max_apr"`
+
+	// Position that produced MaxAPR and its range, empty when no position earns an APR
+	MaxAPRPositionID *string  `json:"max_apr_position_id" gorm:"size:80"`
+	MaxAPRTickLower  *int     `json:"max_apr_tick_lower"`
+	MaxAPRTickUpper  *int     `json:"max_apr_tick_upper"`
+	MaxAPRPriceLower *float64 `json:"max_apr_price_lower"` // Token0 price in token1
+	MaxAPRPriceUpper *float64 `json:"max_apr_price_upper"`
+
+	// Farming state of the latest run, used to simulate new deposits
+	PoolID          *uint    `json:"pool_id" gorm:"index"`
+	Pool            *Pool    `json:"pool,omitempty" gorm:"foreignKey:PoolID"`
+	RewardRate      *float64 `json:"reward_rate"`      // Native currency per second
+	ActiveLiquidity *float64 `json:"active_liquidity"` // Sum of in range deposited liquidity
+
+	// APR of a reference deposit once its value is added to the farming TVL
+	MarginalAPR *float64 `json:"marginal_apr"`
+
+	// APR values compounded at the configured frequency
+	APY             *float64 `json:"apy"`
+	MaxAPY          *float64 `json:"max_apy"`
+	APYP25          *float64 `json:"apy_p25" gorm:"column:apy_p25"`
+	APYMedian       *float64 `json:"apy_median" gorm:"column:apy_median"`
+	APYP75          *float64 `json:"apy_p75" gorm:"column:apy_p75"`
+	APYP90          *float64 `json:"apy_p90" gorm:"column:apy_p90"`
+	RealisticMaxAPY *float64 `json:"realistic_max_apy"`
+	MarginalAPY     *float64 `json:"marginal_apy"`
+
+	// Seconds until the reward reserves run out at the current rates, nil if the token is not distributed
+	RewardSecondsRemaining      *float64 `json:"reward_seconds_remaining"`
+	BonusRewardSecondsRemaining *float64 `json:"bonus_reward_seconds_remaining"`
+
+	Rewards []FarmingReward `json:"rewards,omitempty" gorm:"foreignKey:FarmingID"`
+}
+
+// FarmingReward is the APR contributed by a single reward token of an eternal farming
+type FarmingReward struct {
+	BaseModel
+	FarmingID    uint    `json:"-" gorm:"index;not null"`
+	TokenAddress string  `json:"token_address" gorm:"size:42;not null"`
+	Symbol       string  `json:"symbol" gorm:"size:64"`
+	IsBonus      bool    `json:"is_bonus"`
+	TokensPerDay float64 `json:"tokens_per_day"`
+	APR          float64 `json:"apr"`
+}
+
+// PoolSnapshot stores the values computed for a pool during a single APR update run
+type PoolSnapshot struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	PoolID      uint      `json:"pool_id" gorm:"index:idx_pool_snapshots_pool_run;not null"`
+	NetworkID   uint      `json:"network_id" gorm:"index;not null"`
+	RunAt       time.Time `json:"run_at" gorm:"index:idx_pool_snapshots_pool_run;not null"`
+	APR         *float64  `json:"apr"`
+	MaxAPR      *float64  `json:"max_apr"`
+	TVL         *float64  `json:"tvl"`
+	TVLUSD      *float64  `json:"tvl_usd" gorm:"column:tvl_usd"`
+	Fees        *float64  `json:"fees"`
+	FeesUSD     *float64  `json:"fees_usd" gorm:"column:fees_usd"`
+	BlockNumber *int64    `json:"block_number"` // Analytics subgraph block the run was pinned to
+	Pool        Pool      `json:"-" gorm:"foreignKey:PoolID"`
+}
+
+// FarmingSnapshot stores the values computed for an eternal farming during a single APR update run
+type FarmingSnapshot struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	FarmingID   uint      `json:"farming_id" gorm:"index:idx_farming_snapshots_farming_run;not null"`
+	NetworkID   uint      `json:"network_id" gorm:"index;not null"`
+	RunAt       time.Time `json:"run_at" gorm:"index:idx_farming_snapshots_farming_run;not null"`
+	APR         *float64  `json:"apr"`
+	MaxAPR      *float64  `json:"max_apr"`
+	TVL         *float64  `json:"tvl"`
+	TVLUSD      *float64  `json:"tvl_usd" gorm:"column:tvl_usd"`
+	BlockNumber *int64    `json:"block_number"` // Farming subgraph block the run was pinned to
+	Farming     Farming   `json:"-" gorm:"foreignKey:FarmingID"`
+}
+
+// PoolRangeAPR stores the theoretical APR of a preset range around the current price during a single APR update run
+type PoolRangeAPR struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	PoolID     uint      `json:"pool_id" gorm:"index:idx_pool_range_aprs_pool_run;not null"`
+	NetworkID  uint      `json:"network_id" gorm:"index;not null"`
+	RunAt      time.Time `json:"run_at" gorm:"index:idx_pool_range_aprs_pool_run;not null"`
+	Range      string    `json:"range" gorm:"size:8;not null"` // full or ±percent of the current price
+	TickLower  int       `json:"tick_lower"`
+	TickUpper  int       `json:"tick_upper"`
+	FeeAPR     float64   `json:"fee_apr"`
+	FarmingAPR float64   `json:"farming_apr"`
+	TotalAPR   float64   `json:"total_apr"`
+	Pool       Pool      `json:"-" gorm:"foreignKey:PoolID"`
+}
+
+// Position stores the latest values of a single Algebra position, refreshed on every APR update run
+type Position struct {
+	BaseModel
+	PositionID  string   `json:"position_id" gorm:"size:78;not null;uniqueIndex:idx_positions_network_position"`
+	PoolAddress string   `json:"pool_address" gorm:"size:42;not null;index"`
+	Owner       string   `json:"owner" gorm:"size:42;index"`
+	Liquidity   string   `json:"liquidity" gorm:"size:80"`
+	TickLower   int      `json:"tick_lower"`
+	TickUpper   int      `json:"tick_upper"`
+	InRange     bool     `json:"in_range"`
+	Value       *float64 `json:"value"`
+	ValueUSD    *float64 `json:"value_usd" gorm:"column:value_usd"`
+	FeeAPR      *float64 `json:"fee_apr"`
+	FarmingHash *string  `json:"farming_hash" gorm:"size:66"`
+	FarmingAPR  *float64 `json:"farming_apr"`
+	TotalAPR    *float64 `json:"total_apr"`
+	NetworkID   uint     `json:"network_id" gorm:"uniqueIndex:idx_positions_network_position"`
 }
 
 func (Pool) TableName() string {
@@ -49,3 +233,23 @@ func (Farming) TableName() string {
 func (Network) TableName() string {
 	return "networks"
 }
+
+func (PoolSnapshot) TableName() string {
+	return "pool_snapshots"
+}
+
+func (FarmingSnapshot) TableName() string {
+	return "farming_snapshots"
+}
+
+func (Position) TableName() string {
+	return "positions"
+}
+
+func (FarmingReward) TableName() string {
+	return "farming_rewards"
+}
+
+func (PoolRangeAPR) TableName() string {
+	return "pool_range_aprs"
+}
@@ -2,6 +2,7 @@ package router
 
 import (
 	"algebra-apr-backend/internal/handlers"
+	"algebra-apr-backend/internal/services"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -9,7 +10,7 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupRouter(db *gorm.DB) *gin.Engine {
+func SetupRouter(db *gorm.DB, aprService *services.APRService) *gin.Engine {
 	r := gin.Default()
 
 	// Configure CORS to allow specific origins
@@ -28,7 +29,7 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 		MaxAge:           12 * time.Hour,
 	}))
 
-	handler := handlers.NewHandler(db)
+	handler := handlers.NewHandler(db, aprService)
 
 	api := r.Group("/api")
 	{
@@ -36,14 +37,35 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 		{
 			pools.GET("/apr", handler.GetPoolsAPR)
 			pools.GET("/max-apr", handler.GetPoolsMaxAPR)
+			pools.GET("/total-apr", handler.GetPoolsTotalAPR)
+			pools.GET("/tvl", handler.GetPoolsTVL)
+			pools.GET("/:address", handler.GetPool)
+			pools.GET("/:address/history", handler.GetPoolHistory)
+			pools.GET("/:address/range-apr", handler.GetPoolRangeAPR)
 		}
 
 		eternalFarmings := api.Group("/eternal-farmings")
 		{
 			eternalFarmings.GET("/apr", handler.GetEternalFarmingsAPR)
+			eternalFarmings.GET("/marginal-apr", handler.GetFarmingsMarginalAPR)
 			eternalFarmings.GET("/max-apr", handler.GetFarmingsMaxAPR)
 			eternalFarmings.GET("/tvl", handler.GetFarmingsTVL)
+			eternalFarmings.GET("/rewards-remaining", handler.GetFarmingsRewardsRemaining)
+			eternalFarmings.GET("/:hash", handler.GetFarming)
+			eternalFarmings.GET("/:hash/history", handler.GetFarmingHistory)
 		}
+
+		positions := api.Group("/positions")
+		{
+			positions.GET("/:id", handler.GetPosition)
+		}
+
+		wallets := api.Group("/wallets")
+		{
+			wallets.GET("/:owner/positions", handler.GetWalletPositions)
+		}
+
+		api.POST("/simulate", handler.SimulateAPR)
 	}
 
 	return r
@@ -5,6 +5,7 @@ import (
 	"algebra-apr-backend/internal/logger"
 	"algebra-apr-backend/internal/models"
 	"algebra-apr-backend/internal/services"
+	"context"
 	"sync"
 	"time"
 
@@ -18,16 +19,26 @@ type Scheduler struct {
 	scheduler  *gocron.Scheduler
 	aprService *services.APRService
 	config     *config.Config
+
+	// Cancelled on Stop to abort in-flight updates. mu orders starting an update
+	// against Stop, so Stop never waits on running while an update is being added.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	running sync.WaitGroup
 }
 
 func NewScheduler(db *gorm.DB, cfg *config.Config, aprService *services.APRService) *Scheduler {
 	s := gocron.NewScheduler(time.UTC)
+	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Scheduler{
 		db:         db,
 		scheduler:  s,
 		aprService: aprService,
 		config:     cfg,
+		ctx:        ctx,
+		cancel:     cancel,
 	}
 }
 
@@ -41,12 +52,36 @@ func (s *Scheduler) Start() {
 	s.scheduler.StartAsync()
 }
 
+// Stop stops scheduling updates, cancels the ones in flight and waits for them to return
 func (s *Scheduler) Stop() {
 	logger.Logger.Info("Stopping scheduler...")
 	s.scheduler.Stop()
+
+	s.mu.Lock()
+	s.cancel()
+	s.mu.Unlock()
+
+	s.running.Wait()
+}
+
+// startUpdate registers an update with running, false once the scheduler is stopping
+func (s *Scheduler) startUpdate() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return false
+	}
+	s.running.Add(1)
+	return true
 }
 
 func (s *Scheduler) updateAllAPR() {
+	if !s.startUpdate() {
+		return
+	}
+	defer s.running.Done()
+
 	logger.Logger.Info("Running unified APR update task")
 
 	var networks []models.Network
@@ -61,7 +96,7 @@ func (s *Scheduler) updateAllAPR() {
 		wg.Add(1)
 		go func(net models.Network) {
 			defer wg.Done()
-			if err := s.aprService.UpdateAllAPR(net.ID); err != nil {
+			if err := s.aprService.UpdateAllAPR(s.ctx, net.ID); err != nil {
 				logger.Logger.Error("Failed to update all APR",
 					zap.String("network", net.Title),
 					zap.Error(err))
@@ -2,12 +2,13 @@ package services
 
 import (
 	"algebra-apr-backend/internal/client"
+	"algebra-apr-backend/internal/config"
 	"algebra-apr-backend/internal/graphql"
 	"algebra-apr-backend/internal/logger"
 	"algebra-apr-backend/internal/models"
 	"algebra-apr-backend/internal/types"
 	"algebra-apr-backend/internal/utils"
-	"encoding/json"
+	"context"
 	"fmt"
 	"math"
 	"strconv"
@@ -15,15 +16,33 @@ import (
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// Fee APR windows in days, computed from poolDayDatas.
+// The 1d window uses the rolling poolHourDatas window when hour data is available.
+const (
+	feeWindow1d  = 1
+	feeWindow7d  = 7
+	feeWindow30d = 30
+)
+
+// poolFeesData holds the fee buckets of every pool fetched during a run, keyed by pool ID
+type poolFeesData struct {
+	days  map[string][]types.PoolDayData
+	hours map[string][]types.PoolHourData // nil when the hour data fetch failed
+	at    time.Time                       // Reference time the fee windows end at
+}
+
 type APRService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	config *config.Config
 }
 
-func NewAPRService(db *gorm.DB) *APRService {
+func NewAPRService(db *gorm.DB, cfg *config.Config) *APRService {
 	return &APRService{
-		db: db,
+		db:     db,
+		config: cfg,
 	}
 }
 
@@ -34,14 +53,29 @@ func (s *APRService) getClientsForNetwork(networkID uint) (*client.GraphQLClient
 		return nil, nil, fmt.Errorf("network not found: %w", err)
 	}
 
-	analyticsClient := client.NewGraphQLClient(network.AnalyticsSubgraphURL, network.APIKey)
-	farmingClient := client.NewGraphQLClient(network.FarmingSubgraphURL, network.APIKey)
+	// Networks missing from the config use the client default timeout and retry policy
+	var timeout time.Duration
+	retry := client.DefaultRetryPolicy
+	if networkConfig, exists := s.config.GetNetwork(network.Title); exists {
+		timeout = networkConfig.RequestTimeout()
+		if networkConfig.Retry != nil {
+			retry = client.RetryPolicy{
+				MaxRetries:     networkConfig.Retry.MaxRetries,
+				InitialBackoff: time.Duration(networkConfig.Retry.InitialBackoffMs) * time.Millisecond,
+				MaxBackoff:     time.Duration(networkConfig.Retry.MaxBackoffMs) * time.Millisecond,
+			}.WithDefaults()
+		}
+	}
+
+	analyticsClient := client.NewGraphQLClient(network.AnalyticsSubgraphURL, network.APIKey, timeout, retry)
+	farmingClient := client.NewGraphQLClient(network.FarmingSubgraphURL, network.APIKey, timeout, retry)
 
 	return analyticsClient, farmingClient, nil
 }
 
 // Calculate all APR values in one go - optimized approach
-func (s *APRService) UpdateAllAPR(networkID uint) error {
+// Subgraph requests are cancelled when ctx is done, in which case the update stops before processing the fetched data.
+func (s *APRService) UpdateAllAPR(ctx context.Context, networkID uint) error {
 	var network models.Network
 	if err := s.db.First(&network, networkID).Error; err != nil {
 		return fmt.Errorf("network not found: %w", err)
@@ -52,73 +86,126 @@ func (s *APRService) UpdateAllAPR(networkID uint) error {
 		return err
 	}
 
+	runAt := time.Now().UTC()
+
 	logger.Logger.Info("Starting full APR update", zap.String("network", network.Title))
 
-	// Get all pools in one request
-	pools, err := s.getAllPools(analyticsClient)
+	blocks, err := s.getRunBlocks(ctx, analyticsClient, farmingClient)
 	if err != nil {
-		return fmt.Errorf("failed to get pools: %w", err)
+		return err
 	}
 
-	// Get pool day data for yesterday
-	poolDayDatas, err := s.getPoolDayDatas(analyticsClient)
-	if err != nil {
-		return fmt.Errorf("failed to get pool day data: %w", err)
-	}
+	// Analytics and farming datasets are independent, fetch them concurrently.
+	// The first failing fetch cancels the others.
+	var (
+		pools              []types.Pool
+		poolDayDatas       []types.PoolDayData
+		poolHourDatas      []types.PoolHourData
+		hourDataAvailable  bool
+		positions          []types.Position
+		farmings           []types.EternalFarming
+		allFarmingDeposits []types.FarmingDeposit
+		rewardTokens       map[string]types.Token
+		nativePriceUSD     *float64
+	)
 
-	// Create map for quick lookup of pool fees
-	poolFeesMap := make(map[string]types.PoolDayData)
-	for _, poolDayData := range poolDayDatas {
-		poolFeesMap[poolDayData.Pool.ID] = poolDayData
-	}
+	fetches := newFetchGroup(ctx, s.config.MaxConcurrentFetches)
 
-	// Get all positions in one request
-	positions, err := s.getAllPositions(analyticsClient)
-	if err != nil {
-		return fmt.Errorf("failed to get positions: %w", err)
-	}
+	// Get all pools
+	fetches.Go(func(ctx context.Context) error {
+		var err error
+		if pools, err = s.getAllPools(ctx, analyticsClient, blocks.Analytics); err != nil {
+			return fmt.Errorf("failed to get pools: %w", err)
+		}
+		return nil
+	})
+
+	// Get pool day data for the configured fee window
+	fetches.Go(func(ctx context.Context) error {
+		var err error
+		if poolDayDatas, err = s.getPoolDayDatas(ctx, analyticsClient, blocks.Analytics, blocks.Time, s.config.FeeWindowDays); err != nil {
+			return fmt.Errorf("failed to get pool day data: %w", err)
+		}
+		return nil
+	})
+
+	// Get pool hour data for the rolling daily fees, falling back to day data when it is unavailable
+	fetches.Go(func(ctx context.Context) error {
+		var err error
+		if poolHourDatas, err = s.getPoolHourDatas(ctx, analyticsClient, blocks.Analytics, blocks.Time, s.config.FeeRollingWindowHours); err != nil {
+			logger.Logger.Warn("Failed to get pool hour data, using pool day data for daily fees", zap.Error(err))
+			return nil
+		}
+		hourDataAvailable = true
+		return nil
+	})
+
+	// Get all positions
+	fetches.Go(func(ctx context.Context) error {
+		var err error
+		if positions, err = s.getAllPositions(ctx, analyticsClient, blocks.Analytics); err != nil {
+			return fmt.Errorf("failed to get positions: %w", err)
+		}
+		return nil
+	})
+
+	// Get all eternal farmings and their reward tokens info
+	fetches.Go(func(ctx context.Context) error {
+		var err error
+		if farmings, err = s.getAllEternalFarmings(ctx, farmingClient, blocks.Farming); err != nil {
+			return fmt.Errorf("failed to get eternal farmings: %w", err)
+		}
 
-	positionsById := make(map[string]types.Position, len(positions))
-	for _, position := range positions {
-		positionsById[position.ID] = position
-	}
+		if rewardTokens, err = s.getRewardTokens(ctx, analyticsClient, blocks.Analytics, farmings); err != nil {
+			return fmt.Errorf("failed to get tokens: %w", err)
+		}
+		return nil
+	})
+
+	// Get all farming positions
+	fetches.Go(func(ctx context.Context) error {
+		var err error
+		if allFarmingDeposits, err = s.getAllFarmingDeposits(ctx, farmingClient, blocks.Farming); err != nil {
+			return fmt.Errorf("failed to get farming positions: %w", err)
+		}
+		return nil
+	})
+
+	// Get native currency price in USD, USD values are left empty if it is unavailable
+	fetches.Go(func(ctx context.Context) error {
+		var err error
+		if nativePriceUSD, err = s.getNativePriceUSD(ctx, analyticsClient, blocks.Analytics); err != nil {
+			logger.Logger.Error("Failed to get native price in USD", zap.Error(err))
+		}
+		return nil
+	})
 
-	// Get all eternal farmings
-	farmings, err := s.getAllEternalFarmings(farmingClient)
-	if err != nil {
-		return fmt.Errorf("failed to get eternal farmings: %w", err)
+	if err := fetches.Wait(); err != nil {
+		return err
 	}
 
-	// Get all farming positions in one request
-	allFarmingDeposits, err := s.getAllFarmingDeposits(farmingClient)
-	if err != nil {
-		return fmt.Errorf("failed to get farming positions: %w", err)
+	// Create maps for quick lookup of pool fees
+	poolFees := poolFeesData{days: make(map[string][]types.PoolDayData), at: blocks.Time}
+	for _, poolDayData := range poolDayDatas {
+		poolFees.days[poolDayData.Pool.ID] = append(poolFees.days[poolDayData.Pool.ID], poolDayData)
 	}
 
-	// Get all reward tokens info
-	rewardTokenAddresses := make(map[string]bool)
-	for _, farming := range farmings {
-		rewardTokenAddresses[farming.RewardToken] = true
-		if farming.BonusRewardToken != "0x0000000000000000000000000000000000000000" {
-			rewardTokenAddresses[farming.BonusRewardToken] = true
+	// Pools without hour buckets had no swaps in the rolling window, day data is only used when the fetch failed
+	if hourDataAvailable {
+		poolFees.hours = make(map[string][]types.PoolHourData)
+		for _, poolHourData := range poolHourDatas {
+			poolFees.hours[poolHourData.Pool.ID] = append(poolFees.hours[poolHourData.Pool.ID], poolHourData)
 		}
 	}
 
-	addresses := make([]string, 0, len(rewardTokenAddresses))
-	for addr := range rewardTokenAddresses {
-		addresses = append(addresses, addr)
+	positionsById := make(map[string]types.Position, len(positions))
+	for _, position := range positions {
+		positionsById[position.ID] = position
 	}
 
-	var rewardTokens map[string]types.Token
-	if len(addresses) > 0 {
-		tokensList, err := s.getTokens(analyticsClient, addresses)
-		if err != nil {
-			return fmt.Errorf("failed to get tokens: %w", err)
-		}
-		rewardTokens = make(map[string]types.Token)
-		for _, token := range tokensList {
-			rewardTokens[token.ID] = token
-		}
+	// Don't save partial results of an update cancelled during the fetches
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("APR update cancelled: %w", err)
 	}
 
 	logger.Logger.Info("Fetched all data",
@@ -126,35 +213,69 @@ func (s *APRService) UpdateAllAPR(networkID uint) error {
 		zap.Int("positions", len(positions)),
 		zap.Int("farmings", len(farmings)),
 		zap.Int("reward_tokens", len(rewardTokens)),
+		zap.Int64("analytics_block", blocks.Analytics),
+		zap.Int64("farming_block", blocks.Farming),
 	)
 
 	// Now process all calculations with the fetched data
-	err = s.processPoolsAPR(pools, positions, poolFeesMap, networkID)
+	err = s.processPoolsAPR(pools, positions, poolFees, nativePriceUSD, networkID)
 	if err != nil {
 		logger.Logger.Error("Failed to process pools APR", zap.Error(err))
 	}
 
-	err = s.processPoolsMaxAPR(pools, positions, poolFeesMap, networkID)
+	maxAPRFilter := s.getMaxAPRFilter(network.Title, nativePriceUSD)
+
+	err = s.processPoolsMaxAPR(pools, positions, poolFees, maxAPRFilter, networkID)
 	if err != nil {
 		logger.Logger.Error("Failed to process pools max APR", zap.Error(err))
 	}
 
-	err = s.processFarmingsAPR(farmings, allFarmingDeposits, positionsById, rewardTokens, networkID)
+	err = s.processFarmingsAPR(farmings, allFarmingDeposits, positionsById, rewardTokens, nativePriceUSD, networkID, blocks.Time)
 	if err != nil {
 		logger.Logger.Error("Failed to process farmings APR", zap.Error(err))
 	}
 
-	err = s.processFarmingsMaxAPR(farmings, allFarmingDeposits, positionsById, rewardTokens, networkID)
+	err = s.processFarmingsMaxAPR(farmings, allFarmingDeposits, positionsById, rewardTokens, maxAPRFilter, networkID, blocks.Time)
 	if err != nil {
 		logger.Logger.Error("Failed to process farmings max APR", zap.Error(err))
 	}
 
+	err = s.processPoolsTotalAPR(pools, networkID)
+	if err != nil {
+		logger.Logger.Error("Failed to process pools total APR", zap.Error(err))
+	}
+
+	err = s.processPoolsRangeAPR(networkID, runAt)
+	if err != nil {
+		logger.Logger.Error("Failed to process pools range APR", zap.Error(err))
+	}
+
+	err = s.processPositionsAPR(pools, positions, poolFees, farmings, allFarmingDeposits, positionsById, rewardTokens, nativePriceUSD, networkID, blocks.Time, runAt)
+	if err != nil {
+		logger.Logger.Error("Failed to process positions APR", zap.Error(err))
+	}
+
+	err = s.processSnapshots(pools, farmings, blocks, networkID, runAt)
+	if err != nil {
+		logger.Logger.Error("Failed to process APR snapshots", zap.Error(err))
+	}
+
+	// Record the native price and blocks only once the results computed from them are stored
+	if nativePriceUSD != nil {
+		network.NativePriceUSD = nativePriceUSD
+	}
+	network.AnalyticsBlockNumber = &blocks.Analytics
+	network.FarmingBlockNumber = &blocks.Farming
+	if err := s.db.Save(&network).Error; err != nil {
+		logger.Logger.Error("Failed to save network", zap.Error(err))
+	}
+
 	logger.Logger.Info("Completed full APR update", zap.String("network", network.Title))
 	return nil
 }
 
 // Process pools APR calculation
-func (s *APRService) processPoolsAPR(pools []types.Pool, positions []types.Position, poolFeesMap map[string]types.PoolDayData, networkID uint) error {
+func (s *APRService) processPoolsAPR(pools []types.Pool, positions []types.Position, poolFees poolFeesData, nativePriceUSD *float64, networkID uint) error {
 	logger.Logger.Info("Processing pools APR")
 
 	// Group positions by pool ID for efficient lookup
@@ -173,16 +294,37 @@ func (s *APRService) processPoolsAPR(pools []types.Pool, positions []types.Posit
 
 		// Calculate TVL and APR
 		tvl := s.calculatePoolTVLFromPositions(poolData, poolPositions)
-		fees := s.calculatePoolFeesFromData(poolData, poolFeesMap)
-
-		if tvl > 0 {
-			apr := (fees * 365 / tvl) * 100
-			pool.LastAPR = &apr
-		} else {
-			apr := 0.0
-			pool.LastAPR = &apr
-		}
-
+		fees := s.calculatePoolFeesFromData(poolData, poolFees, feeWindow1d)
+
+		apr := s.calculatePoolFeeAPR(fees, tvl)
+		apr7d := s.calculatePoolFeeAPR(s.calculatePoolFeesFromData(poolData, poolFees, feeWindow7d), tvl)
+		apr30d := s.calculatePoolFeeAPR(s.calculatePoolFeesFromData(poolData, poolFees, feeWindow30d), tvl)
+		pool.LastAPR = &apr
+		pool.APR7d = &apr7d
+		pool.APR30d = &apr30d
+		pool.APY = s.toAPY(pool.LastAPR)
+		pool.APY7d = s.toAPY(pool.APR7d)
+		pool.APY30d = s.toAPY(pool.APR30d)
+
+		pool.TVL = &tvl
+		pool.Fees = &fees
+		pool.TVLUSD = toUSD(tvl, nativePriceUSD)
+		pool.FeesUSD = toUSD(fees, nativePriceUSD)
+
+		tick, _ := strconv.Atoi(poolData.Tick)
+		tickSpacing, _ := strconv.Atoi(poolData.TickSpacing)
+		decimals0, _ := strconv.Atoi(poolData.Token0.Decimals)
+		decimals1, _ := strconv.Atoi(poolData.Token1.Decimals)
+		derivedMatic0, _ := strconv.ParseFloat(poolData.Token0.DerivedMatic, 64)
+		derivedMatic1, _ := strconv.ParseFloat(poolData.Token1.DerivedMatic, 64)
+		pool.Tick = &tick
+		pool.TickSpacing = tickSpacing
+		pool.SqrtPrice = poolData.SqrtPrice
+		pool.Liquidity = poolData.Liquidity
+		pool.Token0Decimals = decimals0
+		pool.Token1Decimals = decimals1
+		pool.Token0DerivedMatic = &derivedMatic0
+		pool.Token1DerivedMatic = &derivedMatic1
 		s.db.Save(&pool)
 	}
 
@@ -191,7 +333,7 @@ func (s *APRService) processPoolsAPR(pools []types.Pool, positions []types.Posit
 }
 
 // Process pools max APR calculation
-func (s *APRService) processPoolsMaxAPR(pools []types.Pool, positions []types.Position, poolFeesMap map[string]types.PoolDayData, networkID uint) error {
+func (s *APRService) processPoolsMaxAPR(pools []types.Pool, positions []types.Position, poolFees poolFeesData, filter positionFilter, networkID uint) error {
 	logger.Logger.Info("Processing pools max APR")
 
 	// Group positions by pool ID
@@ -206,8 +348,13 @@ func (s *APRService) processPoolsMaxAPR(pools []types.Pool, positions []types.Po
 		pool := s.findOrCreatePool(poolData, networkID)
 
 		poolPositions := positionsByPool[poolData.ID]
-		maxAPR := s.calculatePoolMaxAPRFromPositions(poolData, poolPositions, poolFeesMap)
-		pool.MaxAPR = &maxAPR
+		distribution := s.calculatePoolAPRDistributionFromPositions(poolData, poolPositions, poolFees, filter)
+		pool.APRP25, pool.APRMedian, pool.APRP75, pool.APRP90, pool.MaxAPR = distribution.pointers()
+		pool.RealisticMaxAPR = &distribution.RealisticMax
+		pool.MaxAPY = s.toAPY(pool.MaxAPR)
+		pool.APYP25, pool.APYMedian, pool.APYP75, pool.APYP90 = s.toAPY(pool.APRP25), s.toAPY(pool.APRMedian), s.toAPY(pool.APRP75), s.toAPY(pool.APRP90)
+		pool.RealisticMaxAPY = s.toAPY(pool.RealisticMaxAPR)
+		pool.MaxAPRPositionID, pool.MaxAPRTickLower, pool.MaxAPRTickUpper, pool.MaxAPRPriceLower, pool.MaxAPRPriceUpper = distribution.MaxPosition.pointers()
 
 		s.db.Save(&pool)
 	}
@@ -217,13 +364,27 @@ func (s *APRService) processPoolsMaxAPR(pools []types.Pool, positions []types.Po
 }
 
 // Process farmings APR calculation
-func (s *APRService) processFarmingsAPR(farmings []types.EternalFarming, allFarmingDeposits []types.FarmingDeposit, positionsById map[string]types.Position, rewardTokens map[string]types.Token, networkID uint) error {
+func (s *APRService) processFarmingsAPR(farmings []types.EternalFarming, allFarmingDeposits []types.FarmingDeposit, positionsById map[string]types.Position, rewardTokens map[string]types.Token, nativePriceUSD *float64, networkID uint, blockTime time.Time) error {
 	logger.Logger.Info("Processing farmings APR")
 
 	// Group farming positions by farming ID
 	positionsByFarming := make(map[string][]types.Position)
 	for _, farmingDeposit := range allFarmingDeposits {
-		positionsByFarming[farmingDeposit.EternalFarming] = append(positionsByFarming[farmingDeposit.EternalFarming], positionsById[farmingDeposit.PositionID])
+		position, exists := positionsById[farmingDeposit.PositionID]
+		if !exists {
+			continue
+		}
+		positionsByFarming[farmingDeposit.EternalFarming] = append(positionsByFarming[farmingDeposit.EternalFarming], position)
+	}
+
+	// Map pool addresses to pool records to link farmings to their pool
+	var pools []models.Pool
+	if err := s.db.Where("network_id = ?", networkID).Find(&pools).Error; err != nil {
+		return fmt.Errorf("failed to load pools: %w", err)
+	}
+	poolIDs := make(map[string]uint, len(pools))
+	for _, pool := range pools {
+		poolIDs[pool.Address] = pool.ID
 	}
 
 	// Calculate APR for each farming
@@ -232,18 +393,68 @@ func (s *APRService) processFarmingsAPR(farmings []types.EternalFarming, allFarm
 
 		farmingPositions := positionsByFarming[farmingData.ID]
 		tvl := s.calculateFarmingActiveTVLFromPositions(farmingPositions)
-		rewardRate := s.calculateFarmingRewardRateFromData(farmingData, rewardTokens)
+		activeLiquidity := s.calculateFarmingActiveLiquidityFromPositions(farmingPositions)
+
+		status := calculateFarmingStatus(farmingData, blockTime)
+		farming.Status = status
+		farming.StartTime = parseTimestamp(farmingData.StartTime)
+		farming.EndTime = parseTimestamp(farmingData.EndTime)
+
+		// Inactive incentives don't distribute rewards, so APR is not computed for them
+		rewardRate := 0.0
+		rewards := make([]models.FarmingReward, 0, 2)
+		if status == models.FarmingStatusActive {
+			for _, reward := range s.calculateFarmingRewardsFromData(farmingData, rewardTokens) {
+				rewardRate += reward.NativePerSecond
+
+				rewards = append(rewards, models.FarmingReward{
+					TokenAddress: reward.Token.ID,
+					Symbol:       reward.Token.Symbol,
+					IsBonus:      reward.IsBonus,
+					TokensPerDay: reward.TokensPerSecond * 60 * 60 * 24,
+					APR:          calculateRewardAPR(reward.NativePerSecond, tvl),
+				})
+			}
+		}
 
-		if tvl > 0 {
-			apr := (rewardRate * 60 * 60 * 24 * 365 / tvl) * 100
+		if status != models.FarmingStatusActive {
+			apr := 0.0
+			farming.LastAPR = &apr
+		} else if tvl > 0 {
+			apr := calculateRewardAPR(rewardRate, tvl)
 			farming.LastAPR = &apr
 		} else {
 			apr := -1.0
 			farming.LastAPR = &apr
 		}
 
+		farming.APY = s.toAPY(farming.LastAPR)
+
+		// Marginal APR needs the reference deposit in native currency
+		farming.MarginalAPR = nil
+		if nativePriceUSD != nil && *nativePriceUSD > 0 {
+			marginalAPR := s.calculateFarmingMarginalAPR(rewardRate, tvl, s.config.ReferenceDepositUSD / *nativePriceUSD)
+			farming.MarginalAPR = &marginalAPR
+		}
+		farming.MarginalAPY = s.toAPY(farming.MarginalAPR)
+
 		farming.TVL = &tvl
+		farming.TVLUSD = toUSD(tvl, nativePriceUSD)
+		farming.RewardRate = &rewardRate
+		farming.ActiveLiquidity = &activeLiquidity
+		farming.RewardSecondsRemaining = calculateRewardSecondsRemaining(farmingData.RewardReserve0, farmingData.RewardRate)
+		farming.BonusRewardSecondsRemaining = nil
+		if farmingData.BonusRewardToken != "0x0000000000000000000000000000000000000000" {
+			farming.BonusRewardSecondsRemaining = calculateRewardSecondsRemaining(farmingData.RewardReserve1, farmingData.BonusRewardRate)
+		}
+		if poolID, exists := poolIDs[farmingData.Pool.ID]; exists {
+			farming.PoolID = &poolID
+		}
 		s.db.Save(&farming)
+
+		if err := s.replaceFarmingRewards(farming.ID, rewards); err != nil {
+			logger.Logger.Error("Failed to save farming rewards", zap.String("farming", farming.Hash), zap.Error(err))
+		}
 	}
 
 	logger.Logger.Info("Completed farmings APR processing")
@@ -251,22 +462,34 @@ func (s *APRService) processFarmingsAPR(farmings []types.EternalFarming, allFarm
 }
 
 // Process farmings max APR calculation
-func (s *APRService) processFarmingsMaxAPR(farmings []types.EternalFarming, allFarmingDeposits []types.FarmingDeposit, positionsById map[string]types.Position, rewardTokens map[string]types.Token, networkID uint) error {
+func (s *APRService) processFarmingsMaxAPR(farmings []types.EternalFarming, allFarmingDeposits []types.FarmingDeposit, positionsById map[string]types.Position, rewardTokens map[string]types.Token, filter positionFilter, networkID uint, blockTime time.Time) error {
 	logger.Logger.Info("Processing farmings max APR")
 
 	// Group farming positions by farming ID
 	positionsByFarming := make(map[string][]types.Position)
 	for _, farmingDeposit := range allFarmingDeposits {
-		positionsByFarming[farmingDeposit.EternalFarming] = append(positionsByFarming[farmingDeposit.EternalFarming], positionsById[farmingDeposit.PositionID])
+		position, exists := positionsById[farmingDeposit.PositionID]
+		if !exists {
+			continue
+		}
+		positionsByFarming[farmingDeposit.EternalFarming] = append(positionsByFarming[farmingDeposit.EternalFarming], position)
 	}
 
 	// Calculate max APR for each farming
 	for _, farmingData := range farmings {
 		farming := s.findOrCreateEternalFarming(farmingData, networkID)
 
-		farmingPositions := positionsByFarming[farmingData.ID]
-		maxAPR := s.calculateFarmingMaxAPRFromPositions(farmingData, farmingPositions, rewardTokens)
-		farming.MaxAPR = &maxAPR
+		distribution := APRDistribution{}
+		if calculateFarmingStatus(farmingData, blockTime) == models.FarmingStatusActive {
+			farmingPositions := positionsByFarming[farmingData.ID]
+			distribution = s.calculateFarmingAPRDistributionFromPositions(farmingData, farmingPositions, rewardTokens, filter)
+		}
+		farming.APRP25, farming.APRMedian, farming.APRP75, farming.APRP90, farming.MaxAPR = distribution.pointers()
+		farming.RealisticMaxAPR = &distribution.RealisticMax
+		farming.MaxAPY = s.toAPY(farming.MaxAPR)
+		farming.APYP25, farming.APYMedian, farming.APYP75, farming.APYP90 = s.toAPY(farming.APRP25), s.toAPY(farming.APRMedian), s.toAPY(farming.APRP75), s.toAPY(farming.APRP90)
+		farming.RealisticMaxAPY = s.toAPY(farming.RealisticMaxAPR)
+		farming.MaxAPRPositionID, farming.MaxAPRTickLower, farming.MaxAPRTickUpper, farming.MaxAPRPriceLower, farming.MaxAPRPriceUpper = distribution.MaxPosition.pointers()
 
 		s.db.Save(&farming)
 	}
@@ -275,231 +498,441 @@ func (s *APRService) processFarmingsMaxAPR(farmings []types.EternalFarming, allF
 	return nil
 }
 
-// Data fetching methods using GraphQL client
-func (s *APRService) getAllPools(analyticsClient *client.GraphQLClient) ([]types.Pool, error) {
-	var allPools []types.Pool
-	const pageSize = 1000
-	lastID := "0"
-
-	for {
-		variables := map[string]interface{}{
-			"first": pageSize,
-		}
+// Process pools total APR calculation: fee APR plus the APR of all active farmings of the pool
+func (s *APRService) processPoolsTotalAPR(pools []types.Pool, networkID uint) error {
+	logger.Logger.Info("Processing pools total APR")
 
-		variables["id_gt"] = lastID
+	var farmings []models.Farming
+	if err := s.db.Where("network_id = ? AND status = ? AND pool_id IS NOT NULL", networkID, models.FarmingStatusActive).Find(&farmings).Error; err != nil {
+		return fmt.Errorf("failed to load farmings: %w", err)
+	}
 
-		result, err := analyticsClient.Execute(graphql.PoolsQuery, variables)
-		if err != nil {
-			return nil, err
+	farmingAPRByPool := make(map[uint]float64)
+	for _, farming := range farmings {
+		// Farmings without active liquidity report -1 and don't add to the pool APR
+		if farming.LastAPR != nil && *farming.LastAPR > 0 {
+			farmingAPRByPool[*farming.PoolID] += *farming.LastAPR
 		}
+	}
 
-		var response types.PoolsResponse
-		jsonData, _ := json.Marshal(result.Data)
-		if err := json.Unmarshal(jsonData, &response); err != nil {
-			return nil, err
-		}
+	for _, poolData := range pools {
+		pool := s.findOrCreatePool(poolData, networkID)
 
-		if len(response.Pools) == 0 {
-			break
+		feeAPR := 0.0
+		if pool.LastAPR != nil {
+			feeAPR = *pool.LastAPR
 		}
+		farmingAPR := farmingAPRByPool[pool.ID]
+		totalAPR := feeAPR + farmingAPR
 
-		allPools = append(allPools, response.Pools...)
-
-		// Update lastID for next iteration
-		lastID = response.Pools[len(response.Pools)-1].ID
-
-		if len(response.Pools) < pageSize {
-			break
-		}
+		pool.FarmingAPR = &farmingAPR
+		pool.TotalAPR = &totalAPR
+		pool.FarmingAPY = s.toAPY(pool.FarmingAPR)
+		pool.TotalAPY = s.toAPY(pool.TotalAPR)
+		s.db.Save(&pool)
 	}
 
-	return allPools, nil
+	logger.Logger.Info("Completed pools total APR processing")
+	return nil
 }
 
-func (s *APRService) getPoolDayDatas(analyticsClient *client.GraphQLClient) ([]types.PoolDayData, error) {
-	var allPoolDayDatas []types.PoolDayData
-	const pageSize = 1000
-	lastID := "0"
+// Process per-position APR calculation
+func (s *APRService) processPositionsAPR(pools []types.Pool, positions []types.Position, poolFees poolFeesData, farmings []types.EternalFarming, allFarmingDeposits []types.FarmingDeposit, positionsById map[string]types.Position, rewardTokens map[string]types.Token, nativePriceUSD *float64, networkID uint, blockTime, runAt time.Time) error {
+	logger.Logger.Info("Processing positions APR")
 
-	// Get yesterday's timestamp in seconds
-	yesterday := time.Now().AddDate(0, 0, -1)
-	yesterdayTimestamp := yesterday.Unix() / 86400 * 86400
+	poolsById := make(map[string]types.Pool, len(pools))
+	for _, poolData := range pools {
+		poolsById[poolData.ID] = poolData
+	}
 
-	for {
-		variables := map[string]interface{}{
-			"date":  int(yesterdayTimestamp),
-			"first": pageSize,
+	// Group farming positions by farming ID
+	positionsByFarming := make(map[string][]types.Position)
+	farmingByPosition := make(map[string]string, len(allFarmingDeposits))
+	for _, farmingDeposit := range allFarmingDeposits {
+		position, exists := positionsById[farmingDeposit.PositionID]
+		if !exists {
+			continue
 		}
+		positionsByFarming[farmingDeposit.EternalFarming] = append(positionsByFarming[farmingDeposit.EternalFarming], position)
+		farmingByPosition[farmingDeposit.PositionID] = farmingDeposit.EternalFarming
+	}
 
-		variables["id_gt"] = lastID
-
-		result, err := analyticsClient.Execute(graphql.PoolDayDatasQuery, variables)
-		if err != nil {
-			return nil, err
+	// Reward rate and active liquidity of each farming, shared by all of its deposits
+	type farmingState struct {
+		rewardRate      float64
+		activeLiquidity float64
+	}
+	farmingStates := make(map[string]farmingState, len(farmings))
+	for _, farmingData := range farmings {
+		state := farmingState{
+			activeLiquidity: s.calculateFarmingActiveLiquidityFromPositions(positionsByFarming[farmingData.ID]),
 		}
-
-		var response types.PoolDayDatasResponse
-		jsonData, _ := json.Marshal(result.Data)
-		if err := json.Unmarshal(jsonData, &response); err != nil {
-			return nil, err
+		if calculateFarmingStatus(farmingData, blockTime) == models.FarmingStatusActive {
+			state.rewardRate = s.calculateFarmingRewardRateFromData(farmingData, rewardTokens)
 		}
+		farmingStates[farmingData.ID] = state
+	}
 
-		if len(response.PoolDayDatas) == 0 {
-			break
+	records := make([]models.Position, 0, len(positions))
+	for _, position := range positions {
+		poolData, exists := poolsById[position.Pool.ID]
+		if !exists {
+			continue
 		}
 
-		allPoolDayDatas = append(allPoolDayDatas, response.PoolDayDatas...)
+		positionValue := valuePosition(poolData, position)
+		totalLiquidity, _ := strconv.ParseFloat(poolData.Liquidity, 64)
+		dailyFees := s.calculatePoolFeesFromData(poolData, poolFees, feeWindow1d)
 
-		// Update lastID for next iteration
-		lastID = response.PoolDayDatas[len(response.PoolDayDatas)-1].ID
+		feeAPR := s.calculatePositionFeeAPR(positionValue, dailyFees, totalLiquidity)
+		totalAPR := feeAPR
+		tickLower, _ := strconv.Atoi(position.TickLower.TickIdx)
+		tickUpper, _ := strconv.Atoi(position.TickUpper.TickIdx)
 
-		if len(response.PoolDayDatas) < pageSize {
-			break
+		record := models.Position{
+			PositionID:  position.ID,
+			PoolAddress: poolData.ID,
+			Owner:       position.Owner,
+			Liquidity:   position.Liquidity,
+			TickLower:   tickLower,
+			TickUpper:   tickUpper,
+			InRange:     positionValue.InRange,
+			Value:       &positionValue.Value,
+			ValueUSD:    toUSD(positionValue.Value, nativePriceUSD),
+			FeeAPR:      &feeAPR,
+			NetworkID:   networkID,
 		}
-	}
 
-	return allPoolDayDatas, nil
-}
+		if farmingHash, deposited := farmingByPosition[position.ID]; deposited {
+			state := farmingStates[farmingHash]
+			farmingAPR := s.calculatePositionFarmingAPR(positionValue, state.rewardRate, state.activeLiquidity)
+			totalAPR += farmingAPR
 
-func (s *APRService) getAllPositions(analyticsClient *client.GraphQLClient) ([]types.Position, error) {
-	var allPositions []types.Position
-	const pageSize = 1000
-	lastID := "0"
-
-	for {
-		variables := map[string]interface{}{
-			"first": pageSize,
+			record.FarmingHash = &farmingHash
+			record.FarmingAPR = &farmingAPR
 		}
 
-		variables["id_gt"] = lastID
+		record.TotalAPR = &totalAPR
+		records = append(records, record)
+	}
 
-		result, err := analyticsClient.Execute(graphql.PositionsQuery, variables)
+	if len(records) > 0 {
+		err := s.db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "network_id"}, {Name: "position_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"updated_at", "pool_address", "owner", "liquidity", "tick_lower", "tick_upper", "in_range",
+				"value", "value_usd", "fee_apr", "farming_hash", "farming_apr", "total_apr",
+			}),
+		}).CreateInBatches(&records, 500).Error
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("failed to save positions: %w", err)
 		}
+	}
 
-		var response types.PositionsResponse
-		jsonData, _ := json.Marshal(result.Data)
-		if err := json.Unmarshal(jsonData, &response); err != nil {
-			return nil, err
-		}
+	// Remove positions that were closed or fully withdrawn since the previous run
+	if err := s.db.Where("network_id = ? AND updated_at < ?", networkID, runAt).Delete(&models.Position{}).Error; err != nil {
+		return fmt.Errorf("failed to remove stale positions: %w", err)
+	}
 
-		if len(response.Positions) == 0 {
-			break
-		}
+	logger.Logger.Info("Completed positions APR processing", zap.Int("positions", len(records)))
+	return nil
+}
 
-		allPositions = append(allPositions, response.Positions...)
+// Process snapshots of the values computed during this run so APR history is kept
+func (s *APRService) processSnapshots(pools []types.Pool, farmings []types.EternalFarming, blocks runBlocks, networkID uint, runAt time.Time) error {
+	logger.Logger.Info("Processing APR snapshots")
 
-		// Update lastID for next iteration
-		lastID = response.Positions[len(response.Positions)-1].ID
+	poolSnapshots := make([]models.PoolSnapshot, 0, len(pools))
+	for _, poolData := range pools {
+		pool := s.findOrCreatePool(poolData, networkID)
 
-		if len(response.Positions) < pageSize {
-			break
+		poolSnapshots = append(poolSnapshots, models.PoolSnapshot{
+			PoolID:      pool.ID,
+			NetworkID:   networkID,
+			RunAt:       runAt,
+			APR:         pool.LastAPR,
+			MaxAPR:      pool.MaxAPR,
+			TVL:         pool.TVL,
+			TVLUSD:      pool.TVLUSD,
+			Fees:        pool.Fees,
+			FeesUSD:     pool.FeesUSD,
+			BlockNumber: &blocks.Analytics,
+		})
+	}
+
+	if len(poolSnapshots) > 0 {
+		if err := s.db.CreateInBatches(&poolSnapshots, 500).Error; err != nil {
+			return fmt.Errorf("failed to save pool snapshots: %w", err)
 		}
 	}
 
-	return allPositions, nil
-}
+	farmingSnapshots := make([]models.FarmingSnapshot, 0, len(farmings))
+	for _, farmingData := range farmings {
+		farming := s.findOrCreateEternalFarming(farmingData, networkID)
 
-func (s *APRService) getAllEternalFarmings(farmingClient *client.GraphQLClient) ([]types.EternalFarming, error) {
-	var allFarmings []types.EternalFarming
-	const pageSize = 1000
-	lastID := "0"
+		farmingSnapshots = append(farmingSnapshots, models.FarmingSnapshot{
+			FarmingID:   farming.ID,
+			NetworkID:   networkID,
+			RunAt:       runAt,
+			APR:         farming.LastAPR,
+			MaxAPR:      farming.MaxAPR,
+			TVL:         farming.TVL,
+			TVLUSD:      farming.TVLUSD,
+			BlockNumber: &blocks.Farming,
+		})
+	}
 
-	for {
-		variables := map[string]interface{}{
-			"first": pageSize,
+	if len(farmingSnapshots) > 0 {
+		if err := s.db.CreateInBatches(&farmingSnapshots, 500).Error; err != nil {
+			return fmt.Errorf("failed to save farming snapshots: %w", err)
 		}
+	}
 
-		variables["id_gt"] = lastID
+	logger.Logger.Info("Completed APR snapshots processing",
+		zap.Int("pool_snapshots", len(poolSnapshots)),
+		zap.Int("farming_snapshots", len(farmingSnapshots)),
+	)
+	return nil
+}
 
-		result, err := farmingClient.Execute(graphql.FarmingsQuery, variables)
-		if err != nil {
-			return nil, err
-		}
+// runBlocks are the subgraph blocks every query of an update run is pinned to
+type runBlocks struct {
+	Analytics int64
+	Farming   int64
 
-		var response types.EternalFarmingsResponse
-		jsonData, _ := json.Marshal(result.Data)
-		if err := json.Unmarshal(jsonData, &response); err != nil {
-			return nil, err
-		}
+	// Timestamp of the analytics block, the reference time of the fee windows and farming statuses
+	Time time.Time
+}
 
-		if len(response.EternalFarmings) == 0 {
-			break
-		}
+// getRunBlocks pins the run to the latest block of the analytics subgraph, so pools, positions and fees
+// are read from the same state. The farming subgraph is pinned to the same block, or to its latest block
+// when it is behind.
+func (s *APRService) getRunBlocks(ctx context.Context, analyticsClient, farmingClient *client.GraphQLClient) (runBlocks, error) {
+	analyticsBlock, err := s.getBlock(ctx, analyticsClient)
+	if err != nil {
+		return runBlocks{}, fmt.Errorf("failed to get analytics subgraph block: %w", err)
+	}
+
+	farmingBlock, err := s.getBlock(ctx, farmingClient)
+	if err != nil {
+		return runBlocks{}, fmt.Errorf("failed to get farming subgraph block: %w", err)
+	}
+
+	blocks := runBlocks{Analytics: analyticsBlock.Number, Farming: analyticsBlock.Number, Time: time.Now().UTC()}
+	if analyticsBlock.Timestamp > 0 {
+		blocks.Time = time.Unix(analyticsBlock.Timestamp, 0).UTC()
+	}
+	if farmingBlock.Number < analyticsBlock.Number {
+		logger.Logger.Warn("Farming subgraph is behind the analytics subgraph, pinning it to its latest block",
+			zap.Int64("analytics_block", analyticsBlock.Number),
+			zap.Int64("farming_block", farmingBlock.Number),
+		)
+		blocks.Farming = farmingBlock.Number
+	}
 
-		allFarmings = append(allFarmings, response.EternalFarmings...)
+	logger.Logger.Info("Pinned subgraph queries to blocks",
+		zap.Int64("analytics_block", blocks.Analytics),
+		zap.Int64("farming_block", blocks.Farming),
+		zap.Time("block_time", blocks.Time),
+	)
+	return blocks, nil
+}
 
-		// Update lastID for next iteration
-		lastID = response.EternalFarmings[len(response.EternalFarmings)-1].ID
+// getBlock returns the latest block indexed by a subgraph
+func (s *APRService) getBlock(ctx context.Context, graphqlClient *client.GraphQLClient) (types.Block, error) {
+	var response types.MetaResponse
+	if err := graphqlClient.ExecuteInto(ctx, graphql.MetaQuery, nil, &response); err != nil {
+		return types.Block{}, err
+	}
 
-		if len(response.EternalFarmings) < pageSize {
-			break
-		}
+	if response.Meta.Block.Number <= 0 {
+		return types.Block{}, fmt.Errorf("subgraph returned no block")
 	}
 
-	return allFarmings, nil
+	return response.Meta.Block, nil
 }
 
-func (s *APRService) getAllFarmingDeposits(farmingClient *client.GraphQLClient) ([]types.FarmingDeposit, error) {
-	var allFarmingDeposits []types.FarmingDeposit
-	const pageSize = 1000
-	lastID := "0"
+// blockHeight returns the block argument pinning a subgraph query to a block number
+func blockHeight(number int64) map[string]interface{} {
+	return map[string]interface{}{"number": number}
+}
 
-	for {
-		variables := map[string]interface{}{
-			"first": pageSize,
-		}
+// Data fetching methods using GraphQL client
+func (s *APRService) getAllPools(ctx context.Context, analyticsClient *client.GraphQLClient, block int64) ([]types.Pool, error) {
+	pools, stats, err := client.FetchAll(ctx, analyticsClient, client.PageQuery[types.PoolsResponse, types.Pool]{
+		Query:     graphql.PoolsQuery,
+		Variables: map[string]interface{}{"block": blockHeight(block)},
+		Items:     func(response types.PoolsResponse) []types.Pool { return response.Pools },
+		ID:        func(pool types.Pool) string { return pool.ID },
+	})
+	logFetch("pools", stats)
+	return pools, err
+}
 
-		variables["id_gt"] = lastID
+// getPoolDayDatas returns the day buckets of the last complete days up to the start of the day of at
+func (s *APRService) getPoolDayDatas(ctx context.Context, analyticsClient *client.GraphQLClient, block int64, at time.Time, days int) ([]types.PoolDayData, error) {
+	todayTimestamp := at.Unix() / 86400 * 86400
+	fromTimestamp := todayTimestamp - int64(days)*86400
+
+	poolDayDatas, stats, err := client.FetchAll(ctx, analyticsClient, client.PageQuery[types.PoolDayDatasResponse, types.PoolDayData]{
+		Query: graphql.PoolDayDatasQuery,
+		Variables: map[string]interface{}{
+			"date_gte": int(fromTimestamp),
+			"date_lt":  int(todayTimestamp),
+			"block":    blockHeight(block),
+		},
+		Items: func(response types.PoolDayDatasResponse) []types.PoolDayData { return response.PoolDayDatas },
+		ID:    func(poolDayData types.PoolDayData) string { return poolDayData.ID },
+	})
+	logFetch("pool day data", stats)
+	return poolDayDatas, err
+}
 
-		result, err := farmingClient.Execute(graphql.AllFarmingPositionsQuery, variables)
-		if err != nil {
-			return nil, err
-		}
+// getPoolHourDatas returns the hour buckets of the last complete hours up to the start of the hour of at
+func (s *APRService) getPoolHourDatas(ctx context.Context, analyticsClient *client.GraphQLClient, block int64, at time.Time, hours int) ([]types.PoolHourData, error) {
+	currentHourTimestamp := at.Unix() / 3600 * 3600
+	fromTimestamp := currentHourTimestamp - int64(hours)*3600
+
+	poolHourDatas, stats, err := client.FetchAll(ctx, analyticsClient, client.PageQuery[types.PoolHourDatasResponse, types.PoolHourData]{
+		Query: graphql.PoolHourDatasQuery,
+		Variables: map[string]interface{}{
+			"periodStartUnix_gte": int(fromTimestamp),
+			"periodStartUnix_lt":  int(currentHourTimestamp),
+			"block":               blockHeight(block),
+		},
+		Items: func(response types.PoolHourDatasResponse) []types.PoolHourData { return response.PoolHourDatas },
+		ID:    func(poolHourData types.PoolHourData) string { return poolHourData.ID },
+	})
+	logFetch("pool hour data", stats)
+	return poolHourDatas, err
+}
 
-		var response types.AllFarmingPositionsResponse
-		jsonData, _ := json.Marshal(result.Data)
-		if err := json.Unmarshal(jsonData, &response); err != nil {
-			return nil, err
-		}
+func (s *APRService) getAllPositions(ctx context.Context, analyticsClient *client.GraphQLClient, block int64) ([]types.Position, error) {
+	positions, stats, err := client.FetchAll(ctx, analyticsClient, client.PageQuery[types.PositionsResponse, types.Position]{
+		Query:     graphql.PositionsQuery,
+		Variables: map[string]interface{}{"block": blockHeight(block)},
+		Items:     func(response types.PositionsResponse) []types.Position { return response.Positions },
+		ID:        func(position types.Position) string { return position.ID },
+	})
+	logFetch("positions", stats)
+	return positions, err
+}
 
-		if len(response.FarmingsDeposits) == 0 {
-			break
-		}
+func (s *APRService) getAllEternalFarmings(ctx context.Context, farmingClient *client.GraphQLClient, block int64) ([]types.EternalFarming, error) {
+	farmings, stats, err := client.FetchAll(ctx, farmingClient, client.PageQuery[types.EternalFarmingsResponse, types.EternalFarming]{
+		Query:     graphql.FarmingsQuery,
+		Variables: map[string]interface{}{"block": blockHeight(block)},
+		Items:     func(response types.EternalFarmingsResponse) []types.EternalFarming { return response.EternalFarmings },
+		ID:        func(farming types.EternalFarming) string { return farming.ID },
+	})
+	logFetch("eternal farmings", stats)
+	return farmings, err
+}
 
-		allFarmingDeposits = append(allFarmingDeposits, response.FarmingsDeposits...)
+func (s *APRService) getAllFarmingDeposits(ctx context.Context, farmingClient *client.GraphQLClient, block int64) ([]types.FarmingDeposit, error) {
+	deposits, stats, err := client.FetchAll(ctx, farmingClient, client.PageQuery[types.AllFarmingPositionsResponse, types.FarmingDeposit]{
+		Query:     graphql.AllFarmingPositionsQuery,
+		Variables: map[string]interface{}{"block": blockHeight(block)},
+		Items: func(response types.AllFarmingPositionsResponse) []types.FarmingDeposit {
+			return response.FarmingsDeposits
+		},
+		ID: func(deposit types.FarmingDeposit) string { return deposit.PositionID },
+	})
+	logFetch("farming deposits", stats)
+	return deposits, err
+}
 
-		// Update lastID for next iteration
-		lastID = response.FarmingsDeposits[len(response.FarmingsDeposits)-1].PositionID
+// logFetch logs the page count and latency of a paginated fetch
+func logFetch(entity string, stats client.PaginationStats) {
+	logger.Logger.Debug("Fetched "+entity,
+		zap.Int("pages", stats.Pages),
+		zap.Int("items", stats.Items),
+		zap.Duration("duration", stats.Duration),
+	)
+}
 
-		if len(response.FarmingsDeposits) < pageSize {
-			break
+// getRewardTokens returns the reward and bonus reward tokens of farmings by address
+func (s *APRService) getRewardTokens(ctx context.Context, analyticsClient *client.GraphQLClient, block int64, farmings []types.EternalFarming) (map[string]types.Token, error) {
+	rewardTokenAddresses := make(map[string]bool)
+	for _, farming := range farmings {
+		rewardTokenAddresses[farming.RewardToken] = true
+		if farming.BonusRewardToken != "0x0000000000000000000000000000000000000000" {
+			rewardTokenAddresses[farming.BonusRewardToken] = true
 		}
 	}
 
-	return allFarmingDeposits, nil
-}
+	if len(rewardTokenAddresses) == 0 {
+		return nil, nil
+	}
 
-func (s *APRService) getTokens(analyticsClient *client.GraphQLClient, addresses []string) ([]types.Token, error) {
-	variables := map[string]interface{}{
-		"addresses": addresses,
+	addresses := make([]string, 0, len(rewardTokenAddresses))
+	for addr := range rewardTokenAddresses {
+		addresses = append(addresses, addr)
 	}
 
-	result, err := analyticsClient.Execute(graphql.TokensQuery, variables)
+	tokensList, err := s.getTokens(ctx, analyticsClient, block, addresses)
 	if err != nil {
 		return nil, err
 	}
 
+	rewardTokens := make(map[string]types.Token, len(tokensList))
+	for _, token := range tokensList {
+		rewardTokens[token.ID] = token
+	}
+	return rewardTokens, nil
+}
+
+func (s *APRService) getTokens(ctx context.Context, analyticsClient *client.GraphQLClient, block int64, addresses []string) ([]types.Token, error) {
+	variables := map[string]interface{}{
+		"addresses": addresses,
+		"block":     blockHeight(block),
+	}
+
 	var response types.TokensResponse
-	jsonData, _ := json.Marshal(result.Data)
-	if err := json.Unmarshal(jsonData, &response); err != nil {
+	if err := analyticsClient.ExecuteInto(ctx, graphql.TokensQuery, variables, &response); err != nil {
 		return nil, err
 	}
 
 	return response.Tokens, nil
 }
 
+// getNativePriceUSD returns the native currency price in USD from the analytics subgraph bundle
+func (s *APRService) getNativePriceUSD(ctx context.Context, analyticsClient *client.GraphQLClient, block int64) (*float64, error) {
+	var response types.BundlesResponse
+	if err := analyticsClient.ExecuteInto(ctx, graphql.BundleQuery, map[string]interface{}{"block": blockHeight(block)}, &response); err != nil {
+		return nil, err
+	}
+
+	if len(response.Bundles) == 0 {
+		return nil, fmt.Errorf("bundle not found")
+	}
+
+	price, err := strconv.ParseFloat(response.Bundles[0].MaticPriceUSD, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle price %q: %w", response.Bundles[0].MaticPriceUSD, err)
+	}
+
+	return &price, nil
+}
+
+// replaceFarmingRewards replaces the per-token reward breakdown of a farming with the latest values
+func (s *APRService) replaceFarmingRewards(farmingID uint, rewards []models.FarmingReward) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("farming_id = ?", farmingID).Delete(&models.FarmingReward{}).Error; err != nil {
+			return err
+		}
+
+		if len(rewards) == 0 {
+			return nil
+		}
+
+		for i := range rewards {
+			rewards[i].FarmingID = farmingID
+		}
+		return tx.Create(&rewards).Error
+	})
+}
+
 // Helper methods for finding/creating database records
 func (s *APRService) findOrCreatePool(poolData types.Pool, networkID uint) models.Pool {
 	address := poolData.ID
@@ -539,195 +972,429 @@ func (s *APRService) findOrCreateEternalFarming(farmingData types.EternalFarming
 }
 
 // Calculation methods
+
 func (s *APRService) calculatePoolTVLFromPositions(poolData types.Pool, positions []types.Position) float64 {
 	totalTVL := 0.0
 
-	tick, _ := strconv.Atoi(poolData.Tick)
-
 	for _, position := range positions {
-		liquidity, _ := strconv.ParseFloat(position.Liquidity, 64)
-		token0Price, _ := strconv.ParseFloat(poolData.Token0Price, 64)
-		tickLower, _ := strconv.Atoi(position.TickLower.TickIdx)
-		tickUpper, _ := strconv.Atoi(position.TickUpper.TickIdx)
+		positionValue := valuePosition(poolData, position)
 
-		// Check if position is in range
-		if tickLower < tick && tick < tickUpper {
-			amount0, amount1 := utils.GetAmounts(liquidity, tickLower, tickUpper, tick)
+		// Only in range positions are earning fees
+		if positionValue.InRange {
+			totalTVL += positionValue.Value
+		}
+	}
 
-			// Convert to actual token amounts
-			decimals0, _ := strconv.Atoi(poolData.Token0.Decimals)
-			decimals1, _ := strconv.Atoi(poolData.Token1.Decimals)
+	return totalTVL
+}
+
+// calculatePoolFeesFromData returns the average daily fees of a pool over the last `days` complete days.
+// The window is capped by the configured fee window, since older day data is not fetched.
+// The 1d window uses the rolling hour window instead unless hour data is unavailable.
+func (s *APRService) calculatePoolFeesFromData(poolData types.Pool, poolFees poolFeesData, days int) float64 {
+	if days == feeWindow1d && poolFees.hours != nil {
+		return s.calculatePoolRollingFeesFromData(poolData, poolFees)
+	}
 
-			amount0 = amount0 / math.Pow(10, float64(decimals0))
-			amount1 = amount1 / math.Pow(10, float64(decimals1))
+	poolDayDatas, exists := poolFees.days[poolData.ID]
+	if !exists {
+		return 0
+	}
 
-			// Convert to native currency value
-			totalTVL += amount0
-			totalTVL += amount1 * token0Price
+	if days > s.config.FeeWindowDays {
+		days = s.config.FeeWindowDays
+	}
+
+	fromTimestamp := poolFees.at.Unix()/86400*86400 - int64(days)*86400
+
+	totalFees := 0.0
+	for _, poolDayData := range poolDayDatas {
+		if poolDayData.Date < fromTimestamp {
+			continue
 		}
+
+		feesToken0, _ := strconv.ParseFloat(poolDayData.FeesToken0, 64)
+		feesToken1, _ := strconv.ParseFloat(poolDayData.FeesToken1, 64)
+
+		totalFees += valueTokenAmounts(poolData, feesToken0, feesToken1)
 	}
 
-	return totalTVL
+	return totalFees / float64(days)
 }
 
-func (s *APRService) calculatePoolFeesFromData(poolData types.Pool, poolFeesMap map[string]types.PoolDayData) float64 {
-	poolDayData, exists := poolFeesMap[poolData.ID]
-	if !exists {
+// calculatePoolRollingFeesFromData returns the daily fees of a pool over the rolling hour window
+func (s *APRService) calculatePoolRollingFeesFromData(poolData types.Pool, poolFees poolFeesData) float64 {
+	totalFees := 0.0
+	for _, poolHourData := range poolFees.hours[poolData.ID] {
+		feesToken0, _ := strconv.ParseFloat(poolHourData.FeesToken0, 64)
+		feesToken1, _ := strconv.ParseFloat(poolHourData.FeesToken1, 64)
+
+		totalFees += valueTokenAmounts(poolData, feesToken0, feesToken1)
+	}
+
+	return totalFees * 24 / float64(s.config.FeeRollingWindowHours)
+}
+
+func (s *APRService) calculatePoolFeeAPR(dailyFees, tvl float64) float64 {
+	if tvl <= 0 {
 		return 0
 	}
 
-	feesToken0, _ := strconv.ParseFloat(poolDayData.FeesToken0, 64)
-	feesToken1, _ := strconv.ParseFloat(poolDayData.FeesToken1, 64)
-	token0Price, _ := strconv.ParseFloat(poolData.Token0Price, 64)
+	return (dailyFees * 365 / tvl) * 100
+}
+
+// APRDistribution is the liquidity-weighted distribution of the position APRs of a pool or farming
+type APRDistribution struct {
+	P25          float64
+	Median       float64
+	P75          float64
+	P90          float64
+	Max          float64
+	RealisticMax float64 // Max over the positions passing the network's max APR filter
+	MaxPosition  *maxAPRPosition
+}
 
-	return feesToken0 + feesToken1*token0Price
+// maxAPRPosition is the position that produced the max APR of a pool or farming
+type maxAPRPosition struct {
+	ID         string
+	TickLower  int
+	TickUpper  int
+	PriceLower float64 // Token0 price in token1
+	PriceUpper float64
 }
 
-func (s *APRService) calculatePoolMaxAPRFromPositions(poolData types.Pool, positions []types.Position, poolFeesMap map[string]types.PoolDayData) float64 {
-	maxAPR := 0.0
+// newMaxAPRPosition returns the range of a position with the token0 prices at its bounds
+func newMaxAPRPosition(poolData types.Pool, position types.Position, positionValue PositionValue) *maxAPRPosition {
+	decimals0, _ := strconv.Atoi(poolData.Token0.Decimals)
+	decimals1, _ := strconv.Atoi(poolData.Token1.Decimals)
+
+	return &maxAPRPosition{
+		ID:         position.ID,
+		TickLower:  positionValue.TickLower,
+		TickUpper:  positionValue.TickUpper,
+		PriceLower: utils.TickToPrice(positionValue.TickLower, decimals0, decimals1),
+		PriceUpper: utils.TickToPrice(positionValue.TickUpper, decimals0, decimals1),
+	}
+}
 
-	tick, _ := strconv.Atoi(poolData.Tick)
-	totalLiquidity, _ := strconv.ParseFloat(poolData.Liquidity, 64)
-	token0Price, _ := strconv.ParseFloat(poolData.Token0Price, 64)
-	totalFees := s.calculatePoolFeesFromData(poolData, poolFeesMap)
+// pointers returns the position ID, ticks and prices for storing on a model, all nil without a position
+func (p *maxAPRPosition) pointers() (*string, *int, *int, *float64, *float64) {
+	if p == nil {
+		return nil, nil, nil, nil, nil
+	}
+	return &p.ID, &p.TickLower, &p.TickUpper, &p.PriceLower, &p.PriceUpper
+}
 
-	for _, position := range positions {
-		liquidity, _ := strconv.ParseFloat(position.Liquidity, 64)
-		tickLower, _ := strconv.Atoi(position.TickLower.TickIdx)
-		tickUpper, _ := strconv.Atoi(position.TickUpper.TickIdx)
+// calculateAPRDistribution weights every position APR by the position liquidity, so tiny positions
+// in ultra-narrow ranges don't dominate the distribution the way they dominate the max.
+// The max is the unweighted maximum, set by the caller together with its position.
+func calculateAPRDistribution(aprs, liquidities []float64) APRDistribution {
+	return APRDistribution{
+		P25:    utils.WeightedPercentile(aprs, liquidities, 25),
+		Median: utils.WeightedPercentile(aprs, liquidities, 50),
+		P75:    utils.WeightedPercentile(aprs, liquidities, 75),
+		P90:    utils.WeightedPercentile(aprs, liquidities, 90),
+	}
+}
 
-		// Check if position is in range
-		if tickLower < tick && tick < tickUpper {
-			amount0, amount1 := utils.GetAmounts(liquidity, tickLower, tickUpper, tick)
+// positionFilter holds the thresholds a position has to pass to count towards the realistic max APR
+type positionFilter struct {
+	MinValue          float64 // Native currency
+	MinRangeWidth     int     // Ticks
+	MinLiquidityShare float64 // Fraction of the active liquidity
+}
 
-			// Convert to actual token amounts
-			decimals0, _ := strconv.Atoi(poolData.Token0.Decimals)
-			decimals1, _ := strconv.Atoi(poolData.Token1.Decimals)
+// getMaxAPRFilter converts the configured max APR filter of a network to native currency.
+// The minimum value is not applied while the native price in USD is unknown.
+func (s *APRService) getMaxAPRFilter(networkTitle string, nativePriceUSD *float64) positionFilter {
+	networkConfig, exists := s.config.GetNetwork(networkTitle)
+	if !exists {
+		return positionFilter{}
+	}
 
-			amount0 = amount0 / math.Pow(10, float64(decimals0))
-			amount1 = amount1 / math.Pow(10, float64(decimals1))
+	filter := positionFilter{
+		MinRangeWidth:     networkConfig.MaxAPRFilter.MinRangeWidthTicks,
+		MinLiquidityShare: networkConfig.MaxAPRFilter.MinLiquidityShare,
+	}
+	if nativePriceUSD != nil && *nativePriceUSD > 0 {
+		filter.MinValue = networkConfig.MaxAPRFilter.MinPositionValueUSD / *nativePriceUSD
+	}
 
-			positionTVL := amount0 + amount1*token0Price
-			positionFees := totalFees * liquidity / totalLiquidity
+	return filter
+}
 
-			if positionTVL > 0 {
-				apr := (positionFees * 365 / positionTVL) * 100
-				if apr > maxAPR {
-					maxAPR = apr
-				}
-			}
-		}
+// accepts reports whether a position is large and wide enough to count towards the realistic max APR
+func (f positionFilter) accepts(positionValue PositionValue, activeLiquidity float64) bool {
+	if positionValue.Value < f.MinValue {
+		return false
+	}
+	if positionValue.TickUpper-positionValue.TickLower < f.MinRangeWidth {
+		return false
+	}
+	if f.MinLiquidityShare > 0 && (activeLiquidity <= 0 || positionValue.Liquidity/activeLiquidity < f.MinLiquidityShare) {
+		return false
 	}
+	return true
+}
 
-	return maxAPR
+// pointers returns the p25, median, p75, p90 and max values for storing on a model
+func (d APRDistribution) pointers() (*float64, *float64, *float64, *float64, *float64) {
+	return &d.P25, &d.Median, &d.P75, &d.P90, &d.Max
 }
 
-func (s *APRService) calculateFarmingActiveTVLFromPositions(positions []types.Position) float64 {
-	activeTVL := 0.0
+func (s *APRService) calculatePoolAPRDistributionFromPositions(poolData types.Pool, positions []types.Position, poolFees poolFeesData, filter positionFilter) APRDistribution {
+	totalLiquidity, _ := strconv.ParseFloat(poolData.Liquidity, 64)
+	totalFees := s.calculatePoolFeesFromData(poolData, poolFees, feeWindow1d)
 
+	aprs := make([]float64, 0, len(positions))
+	liquidities := make([]float64, 0, len(positions))
+	maxAPR, realisticMaxAPR := 0.0, 0.0
+	var maxPosition *maxAPRPosition
 	for _, position := range positions {
-		tick, _ := strconv.Atoi(position.Pool.Tick)
-		liquidity, _ := strconv.ParseFloat(position.Liquidity, 64)
-		tickLower, _ := strconv.Atoi(position.TickLower.TickIdx)
-		tickUpper, _ := strconv.Atoi(position.TickUpper.TickIdx)
+		positionValue := valuePosition(poolData, position)
 
-		if tick > tickLower && tick < tickUpper {
+		apr := s.calculatePositionFeeAPR(positionValue, totalFees, totalLiquidity)
+		aprs = append(aprs, apr)
+		liquidities = append(liquidities, positionValue.Liquidity)
 
-			amount0, amount1 := utils.GetAmounts(liquidity, tickLower, tickUpper, tick)
+		if apr > maxAPR {
+			maxAPR = apr
+			maxPosition = newMaxAPRPosition(poolData, position, positionValue)
+		}
+		if apr > realisticMaxAPR && filter.accepts(positionValue, totalLiquidity) {
+			realisticMaxAPR = apr
+		}
+	}
+
+	distribution := calculateAPRDistribution(aprs, liquidities)
+	distribution.Max = maxAPR
+	distribution.RealisticMax = realisticMaxAPR
+	distribution.MaxPosition = maxPosition
+	return distribution
+}
 
-			// Convert to actual token amounts
-			decimals0, _ := strconv.Atoi(position.Pool.Token0.Decimals)
-			decimals1, _ := strconv.Atoi(position.Pool.Token1.Decimals)
-			derivedMatic0, _ := strconv.ParseFloat(position.Pool.Token0.DerivedMatic, 64)
-			derivedMatic1, _ := strconv.ParseFloat(position.Pool.Token1.DerivedMatic, 64)
+func (s *APRService) calculateFarmingActiveTVLFromPositions(positions []types.Position) float64 {
+	activeTVL := 0.0
 
-			amount0 = amount0 / math.Pow(10, float64(decimals0))
-			amount1 = amount1 / math.Pow(10, float64(decimals1))
+	for _, position := range positions {
+		positionValue := valuePosition(position.Pool, position)
 
-			// Convert to native currency value
-			activeTVL += amount0 * derivedMatic0
-			activeTVL += amount1 * derivedMatic1
+		if positionValue.InRange {
+			activeTVL += positionValue.Value
 		}
 	}
 
 	return activeTVL
 }
 
-func (s *APRService) calculateFarmingRewardRateFromData(farmingData types.EternalFarming, tokens map[string]types.Token) float64 {
-	rewardRate := 0.0
+// farmingRewardRate is the distribution rate of a single reward token of a farming
+type farmingRewardRate struct {
+	Token           types.Token
+	IsBonus         bool
+	TokensPerSecond float64 // In token units
+	NativePerSecond float64 // In native currency
+}
 
-	// Main reward token
-	if token, exists := tokens[farmingData.RewardToken]; exists {
-		rate, _ := strconv.ParseFloat(farmingData.RewardRate, 64)
-		decimals, _ := strconv.Atoi(token.Decimals)
-		derivedMatic, _ := strconv.ParseFloat(token.DerivedMatic, 64)
+// calculateFarmingRewardsFromData returns the distribution rate of each reward token of a farming.
+// Tokens whose reserve is depleted are returned with a zero rate.
+func (s *APRService) calculateFarmingRewardsFromData(farmingData types.EternalFarming, tokens map[string]types.Token) []farmingRewardRate {
+	rewards := make([]farmingRewardRate, 0, 2)
 
-		rewardRate += (rate / math.Pow(10, float64(decimals))) * derivedMatic
+	// Main reward token, skipped once its reserve is depleted
+	if token, exists := tokens[farmingData.RewardToken]; exists {
+		reward := farmingRewardRate{Token: token}
+		if !isRewardDepleted(farmingData.RewardReserve0, farmingData.RewardRate) {
+			reward.TokensPerSecond, reward.NativePerSecond = calculateTokenRewardRate(farmingData.RewardRate, token)
+		}
+		rewards = append(rewards, reward)
 	}
 
 	// Bonus reward token
 	if farmingData.BonusRewardToken != "0x0000000000000000000000000000000000000000" {
 		if token, exists := tokens[farmingData.BonusRewardToken]; exists {
-			rate, _ := strconv.ParseFloat(farmingData.BonusRewardRate, 64)
-			decimals, _ := strconv.Atoi(token.Decimals)
-			derivedMatic, _ := strconv.ParseFloat(token.DerivedMatic, 64)
-
-			rewardRate += (rate / math.Pow(10, float64(decimals))) * derivedMatic
+			reward := farmingRewardRate{Token: token, IsBonus: true}
+			if !isRewardDepleted(farmingData.RewardReserve1, farmingData.BonusRewardRate) {
+				reward.TokensPerSecond, reward.NativePerSecond = calculateTokenRewardRate(farmingData.BonusRewardRate, token)
+			}
+			rewards = append(rewards, reward)
 		}
 	}
 
+	return rewards
+}
+
+// calculateRewardAPR returns the APR of rewards distributed at nativePerSecond over a TVL, 0 without TVL
+func calculateRewardAPR(nativePerSecond, tvl float64) float64 {
+	if tvl <= 0 {
+		return 0
+	}
+	return (nativePerSecond * 60 * 60 * 24 * 365 / tvl) * 100
+}
+
+// calculateTokenRewardRate converts a raw reward rate to token units and native currency per second
+func calculateTokenRewardRate(rate string, token types.Token) (float64, float64) {
+	rateValue, _ := strconv.ParseFloat(rate, 64)
+	decimals, _ := strconv.Atoi(token.Decimals)
+	derivedMatic, _ := strconv.ParseFloat(token.DerivedMatic, 64)
+
+	tokensPerSecond := rateValue / math.Pow(10, float64(decimals))
+	return tokensPerSecond, tokensPerSecond * derivedMatic
+}
+
+func (s *APRService) calculateFarmingRewardRateFromData(farmingData types.EternalFarming, tokens map[string]types.Token) float64 {
+	rewardRate := 0.0
+
+	for _, reward := range s.calculateFarmingRewardsFromData(farmingData, tokens) {
+		rewardRate += reward.NativePerSecond
+	}
+
 	return rewardRate
 }
 
-func (s *APRService) calculateFarmingMaxAPRFromPositions(farmingData types.EternalFarming, positions []types.Position, tokens map[string]types.Token) float64 {
-	maxAPR := 0.0
+// calculateFarmingStatus returns whether an eternal farming is distributing rewards at the given time
+func calculateFarmingStatus(farmingData types.EternalFarming, now time.Time) string {
+	if farmingData.IsDeactivated {
+		return models.FarmingStatusDeactivated
+	}
+
+	if startTime := parseTimestamp(farmingData.StartTime); startTime != nil && *startTime > now.Unix() {
+		return models.FarmingStatusPending
+	}
+
+	// Eternal farmings without an end time run until they are deactivated
+	if endTime := parseTimestamp(farmingData.EndTime); endTime != nil && *endTime > 0 && *endTime <= now.Unix() {
+		return models.FarmingStatusEnded
+	}
+
+	return models.FarmingStatusActive
+}
 
+// parseTimestamp parses a unix timestamp returned by the subgraph, returning nil when it is missing
+func parseTimestamp(value string) *int64 {
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &timestamp
+}
+
+// calculateRewardSecondsRemaining returns the seconds until a reward reserve runs out at the given rate,
+// or nil when the token is not being distributed
+func calculateRewardSecondsRemaining(reserve, rate string) *float64 {
+	rateValue, err := strconv.ParseFloat(rate, 64)
+	if err != nil || rateValue <= 0 {
+		return nil
+	}
+
+	reserveValue, err := strconv.ParseFloat(reserve, 64)
+	if err != nil {
+		return nil
+	}
+
+	seconds := reserveValue / rateValue
+	return &seconds
+}
+
+// isRewardDepleted reports whether a reward reserve can't cover another second of rewards
+func isRewardDepleted(reserve, rate string) bool {
+	seconds := calculateRewardSecondsRemaining(reserve, rate)
+	return seconds != nil && *seconds < 1
+}
+
+func (s *APRService) calculateFarmingAPRDistributionFromPositions(farmingData types.EternalFarming, positions []types.Position, tokens map[string]types.Token, filter positionFilter) APRDistribution {
 	rewardRate := s.calculateFarmingRewardRateFromData(farmingData, tokens)
-	totalActiveLiquidity := 0.0
 
 	// Calculate total active liquidity
+	positionValues := make([]PositionValue, 0, len(positions))
+	totalActiveLiquidity := 0.0
 	for _, position := range positions {
-		tick, _ := strconv.Atoi(position.Pool.Tick)
-		tickLower, _ := strconv.Atoi(position.TickLower.TickIdx)
-		tickUpper, _ := strconv.Atoi(position.TickUpper.TickIdx)
+		positionValue := valuePosition(position.Pool, position)
+		if positionValue.InRange {
+			totalActiveLiquidity += positionValue.Liquidity
+		}
+		positionValues = append(positionValues, positionValue)
+	}
 
-		if tickLower < tick && tick < tickUpper {
-			liquidity, _ := strconv.ParseFloat(position.Liquidity, 64)
-			totalActiveLiquidity += liquidity
+	// Calculate APR for each position
+	aprs := make([]float64, 0, len(positionValues))
+	liquidities := make([]float64, 0, len(positionValues))
+	maxAPR, realisticMaxAPR := 0.0, 0.0
+	var maxPosition *maxAPRPosition
+	for i, positionValue := range positionValues {
+		apr := s.calculatePositionFarmingAPR(positionValue, rewardRate, totalActiveLiquidity)
+		aprs = append(aprs, apr)
+		liquidities = append(liquidities, positionValue.Liquidity)
+
+		if apr > maxAPR {
+			maxAPR = apr
+			maxPosition = newMaxAPRPosition(positions[i].Pool, positions[i], positionValue)
+		}
+		if apr > realisticMaxAPR && filter.accepts(positionValue, totalActiveLiquidity) {
+			realisticMaxAPR = apr
 		}
 	}
 
-	// Calculate max APR for each position
-	for _, position := range positions {
-		tick, _ := strconv.Atoi(position.Pool.Tick)
-		liquidity, _ := strconv.ParseFloat(position.Liquidity, 64)
-		tickLower, _ := strconv.Atoi(position.TickLower.TickIdx)
-		tickUpper, _ := strconv.Atoi(position.TickUpper.TickIdx)
+	distribution := calculateAPRDistribution(aprs, liquidities)
+	distribution.Max = maxAPR
+	distribution.RealisticMax = realisticMaxAPR
+	distribution.MaxPosition = maxPosition
+	return distribution
+}
 
-		if tickLower < tick && tick < tickUpper {
-			amount0, amount1 := utils.GetAmounts(liquidity, tickLower, tickUpper, tick)
+// toAPY compounds an APR at the configured frequency. Negative APRs mark farmings without
+// active liquidity and are kept as they are.
+func (s *APRService) toAPY(apr *float64) *float64 {
+	if apr == nil {
+		return nil
+	}
+	if *apr < 0 {
+		apy := *apr
+		return &apy
+	}
 
-			// Convert to actual token amounts
-			decimals0, _ := strconv.Atoi(position.Pool.Token0.Decimals)
-			decimals1, _ := strconv.Atoi(position.Pool.Token1.Decimals)
-			derivedMatic0, _ := strconv.ParseFloat(position.Pool.Token0.DerivedMatic, 64)
-			derivedMatic1, _ := strconv.ParseFloat(position.Pool.Token1.DerivedMatic, 64)
+	apy := utils.APRToAPY(*apr, s.config.CompoundingPeriodsPerYear())
+	return &apy
+}
 
-			amount0 = amount0 / math.Pow(10, float64(decimals0))
-			amount1 = amount1 / math.Pow(10, float64(decimals1))
+// calculateFarmingMarginalAPR returns the APR of a new deposit of the given native value, which dilutes
+// the rewards of the current active TVL instead of earning the APR computed without it
+func (s *APRService) calculateFarmingMarginalAPR(rewardRate, tvl, depositValue float64) float64 {
+	if rewardRate <= 0 || tvl+depositValue <= 0 {
+		return 0
+	}
 
-			positionTVL := amount0*derivedMatic0 + amount1*derivedMatic1
+	return (rewardRate * 60 * 60 * 24 * 365 / (tvl + depositValue)) * 100
+}
 
-			if positionTVL > 0 && totalActiveLiquidity > 0 {
-				positionRewardRate := rewardRate * liquidity / totalActiveLiquidity
-				apr := (positionRewardRate * 60 * 60 * 24 * 365 / positionTVL) * 100
+func (s *APRService) calculateFarmingActiveLiquidityFromPositions(positions []types.Position) float64 {
+	activeLiquidity := 0.0
 
-				if apr > maxAPR {
-					maxAPR = apr
-				}
-			}
+	for _, position := range positions {
+		positionValue := valuePosition(position.Pool, position)
+		if positionValue.InRange {
+			activeLiquidity += positionValue.Liquidity
 		}
 	}
 
-	return maxAPR
+	return activeLiquidity
+}
+
+// calculatePositionFeeAPR returns the fee APR of a position earning its share of the pool's active liquidity
+func (s *APRService) calculatePositionFeeAPR(positionValue PositionValue, dailyFees, activeLiquidity float64) float64 {
+	if !positionValue.InRange || positionValue.Value <= 0 || activeLiquidity <= 0 {
+		return 0
+	}
+
+	positionFees := dailyFees * positionValue.Liquidity / activeLiquidity
+	return (positionFees * 365 / positionValue.Value) * 100
+}
+
+// calculatePositionFarmingAPR returns the farming APR of a position earning its share of the farming's active liquidity
+func (s *APRService) calculatePositionFarmingAPR(positionValue PositionValue, rewardRate, activeLiquidity float64) float64 {
+	if !positionValue.InRange || positionValue.Value <= 0 || activeLiquidity <= 0 {
+		return 0
+	}
+
+	positionRewardRate := rewardRate * positionValue.Liquidity / activeLiquidity
+	return (positionRewardRate * 60 * 60 * 24 * 365 / positionValue.Value) * 100
 }
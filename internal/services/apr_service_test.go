@@ -0,0 +1,386 @@
+package services
+
+import (
+	"algebra-apr-backend/internal/client"
+	"algebra-apr-backend/internal/config"
+	"algebra-apr-backend/internal/logger"
+	"algebra-apr-backend/internal/models"
+	"algebra-apr-backend/internal/types"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestRealisticMaxAPRIgnoresDustPositions(t *testing.T) {
+	s := &APRService{config: &config.Config{FeeWindowDays: 30}}
+	pool := testPool()
+	positions := testPositions(pool)
+
+	// Dust position in a 20 tick range earns the highest APR per unit of value
+	positions = append(positions, types.Position{
+		ID:        "4",
+		Liquidity: "1000000000000000",
+		TickLower: types.Tick{TickIdx: "-276310"},
+		TickUpper: types.Tick{TickIdx: "-276290"},
+		Pool:      pool,
+	})
+
+	poolFees := testPoolFees(pool, testRunTime)
+
+	distribution := s.calculatePoolAPRDistributionFromPositions(pool, positions, poolFees, positionFilter{MinRangeWidth: 60})
+
+	dustAPR := s.calculatePositionFeeAPR(valuePosition(pool, positions[3]), s.calculatePoolFeesFromData(pool, poolFees, feeWindow1d), 3e18)
+	if distribution.Max != dustAPR {
+		t.Errorf("max APR = %f, expected the dust position APR %f", distribution.Max, dustAPR)
+	}
+	if distribution.MaxPosition == nil || distribution.MaxPosition.ID != "4" || distribution.MaxPosition.TickLower != -276310 || distribution.MaxPosition.TickUpper != -276290 {
+		t.Errorf("max APR position = %+v, expected the dust position", distribution.MaxPosition)
+	} else if distribution.MaxPosition.PriceLower >= distribution.MaxPosition.PriceUpper {
+		t.Errorf("max APR position price range %f - %f is not ordered", distribution.MaxPosition.PriceLower, distribution.MaxPosition.PriceUpper)
+	}
+	if distribution.RealisticMax <= 0 || distribution.RealisticMax >= distribution.Max {
+		t.Errorf("realistic max APR = %f, expected positive and below max APR %f", distribution.RealisticMax, distribution.Max)
+	}
+	if distribution.Median > distribution.P90 || distribution.P90 > distribution.Max {
+		t.Errorf("APR distribution is not ordered: %+v", distribution)
+	}
+}
+
+func TestMaxAPRIsDustPositionNextToWhale(t *testing.T) {
+	s := &APRService{config: &config.Config{FeeWindowDays: 30}}
+	pool := testPool()
+	pool.Liquidity = "10000000000000000000000000"
+
+	positions := []types.Position{
+		{ID: "whale", Liquidity: "10000000000000000000000000", TickLower: types.Tick{TickIdx: "-887220"}, TickUpper: types.Tick{TickIdx: "887220"}, Pool: pool},
+		{ID: "dust", Liquidity: "1000", TickLower: types.Tick{TickIdx: "-276310"}, TickUpper: types.Tick{TickIdx: "-276290"}, Pool: pool},
+	}
+
+	poolFees := testPoolFees(pool, testRunTime)
+
+	distribution := s.calculatePoolAPRDistributionFromPositions(pool, positions, poolFees, positionFilter{})
+
+	totalFees := s.calculatePoolFeesFromData(pool, poolFees, feeWindow1d)
+	dustAPR := s.calculatePositionFeeAPR(valuePosition(pool, positions[1]), totalFees, 1e25)
+	whaleAPR := s.calculatePositionFeeAPR(valuePosition(pool, positions[0]), totalFees, 1e25)
+	if dustAPR <= whaleAPR {
+		t.Fatalf("dust position APR %f is not above the whale APR %f", dustAPR, whaleAPR)
+	}
+
+	if distribution.Max != dustAPR {
+		t.Errorf("max APR = %f, expected the dust position APR %f", distribution.Max, dustAPR)
+	}
+	if distribution.MaxPosition == nil || distribution.MaxPosition.ID != "dust" {
+		t.Errorf("max APR position = %+v, expected the dust position", distribution.MaxPosition)
+	}
+	if distribution.RealisticMax > distribution.Max {
+		t.Errorf("realistic max APR %f is above max APR %f", distribution.RealisticMax, distribution.Max)
+	}
+}
+
+func TestRangeAPRGrowsAsRangeNarrows(t *testing.T) {
+	s := &APRService{}
+
+	tick := -276300
+	fees := 10.0
+	derivedMatic := 0.5
+	rewardRate := 0.001
+	activeLiquidity := 1e18
+	pool := models.Pool{
+		Tick:               &tick,
+		Liquidity:          "3000000000000000000",
+		Fees:               &fees,
+		Token0Decimals:     18,
+		Token1Decimals:     6,
+		Token0DerivedMatic: &derivedMatic,
+		Token1DerivedMatic: &derivedMatic,
+	}
+	farmings := []models.Farming{{RewardRate: &rewardRate, ActiveLiquidity: &activeLiquidity}}
+
+	previousFeeAPR, previousFarmingAPR := 0.0, 0.0
+	for _, preset := range rangePresets {
+		tickLower, tickUpper := preset.rangeTicks(tick, 60)
+		if tickLower%60 != 0 || tickUpper%60 != 0 {
+			t.Errorf("range %s ticks %d - %d are not multiples of the tick spacing", preset.Name, tickLower, tickUpper)
+		}
+		feeAPR, farmingAPR := s.calculateRangeAPR(pool, farmings, tickLower, tickUpper)
+
+		if feeAPR <= previousFeeAPR || farmingAPR <= previousFarmingAPR {
+			t.Errorf("range %s APR %f/%f is not above the wider range APR %f/%f", preset.Name, feeAPR, farmingAPR, previousFeeAPR, previousFarmingAPR)
+		}
+		previousFeeAPR, previousFarmingAPR = feeAPR, farmingAPR
+	}
+}
+
+func TestPoolDailyFeesPreferRollingHourData(t *testing.T) {
+	s := &APRService{config: &config.Config{FeeWindowDays: 30, FeeRollingWindowHours: 24}}
+	pool := testPool()
+
+	poolFees := testPoolFees(pool, testRunTime)
+
+	if fees := s.calculatePoolFeesFromData(pool, poolFees, feeWindow1d); fees != 10 {
+		t.Errorf("daily fees from day data = %f, expected 10", fees)
+	}
+
+	hourData := make([]types.PoolHourData, 0, 2)
+	for _, fees := range []string{"1", "2"} {
+		data := types.PoolHourData{FeesToken0: fees, FeesToken1: fees}
+		data.Pool.ID = pool.ID
+		hourData = append(hourData, data)
+	}
+	poolFees.hours = map[string][]types.PoolHourData{pool.ID: hourData}
+
+	if fees := s.calculatePoolFeesFromData(pool, poolFees, feeWindow1d); fees != 3 {
+		t.Errorf("daily fees from hour data = %f, expected 3", fees)
+	}
+	if fees := s.calculatePoolFeesFromData(pool, poolFees, feeWindow7d); fees != 10.0/7 {
+		t.Errorf("7d fees = %f, expected day data average %f", fees, 10.0/7)
+	}
+	if fees := s.calculatePoolFeesFromData(types.Pool{ID: "0xnew"}, poolFees, feeWindow1d); fees != 0 {
+		t.Errorf("daily fees of a pool without any fee data = %f, expected 0", fees)
+	}
+
+	// A pool missing from the hour data had no swaps in the rolling window, its older day data is ignored
+	otherPool := testPool()
+	otherPool.ID = "0xother"
+	poolFees.days[otherPool.ID] = testPoolFees(otherPool, testRunTime).days[otherPool.ID]
+	if fees := s.calculatePoolFeesFromData(otherPool, poolFees, feeWindow1d); fees != 0 {
+		t.Errorf("daily fees of a pool without hour data = %f, expected 0", fees)
+	}
+
+	// Empty hour data still counts as available
+	poolFees.hours = map[string][]types.PoolHourData{}
+	if fees := s.calculatePoolFeesFromData(pool, poolFees, feeWindow1d); fees != 0 {
+		t.Errorf("daily fees without any hour buckets = %f, expected 0", fees)
+	}
+}
+
+func TestRunBlocksPinFarmingSubgraphToClosestBlock(t *testing.T) {
+	logger.Logger = zap.NewNop()
+
+	tests := []struct {
+		name            string
+		analyticsBlock  int64
+		farmingBlock    int64
+		expectedFarming int64
+	}{
+		{name: "farming subgraph ahead", analyticsBlock: 1000, farmingBlock: 1005, expectedFarming: 1000},
+		{name: "farming subgraph behind", analyticsBlock: 1000, farmingBlock: 990, expectedFarming: 990},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyticsServer := metaServer(tt.analyticsBlock)
+			defer analyticsServer.Close()
+			farmingServer := metaServer(tt.farmingBlock)
+			defer farmingServer.Close()
+
+			s := &APRService{config: &config.Config{}}
+			blocks, err := s.getRunBlocks(context.Background(),
+				client.NewGraphQLClient(analyticsServer.URL, "", time.Second, client.RetryPolicy{}),
+				client.NewGraphQLClient(farmingServer.URL, "", time.Second, client.RetryPolicy{}),
+			)
+			if err != nil {
+				t.Fatalf("getRunBlocks() returned error: %v", err)
+			}
+			if blocks.Analytics != tt.analyticsBlock || blocks.Farming != tt.expectedFarming {
+				t.Errorf("getRunBlocks() = %+v, expected analytics %d and farming %d", blocks, tt.analyticsBlock, tt.expectedFarming)
+			}
+			if !blocks.Time.Equal(time.Unix(1760572800, 0)) {
+				t.Errorf("getRunBlocks() time = %s, expected the analytics block timestamp", blocks.Time)
+			}
+		})
+	}
+}
+
+// metaServer is a subgraph whose latest indexed block is number
+func metaServer(number int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data": {"_meta": {"block": {"number": %d, "timestamp": 1760572800}}}}`, number)
+	}))
+}
+
+func TestCalculateRewardSecondsRemaining(t *testing.T) {
+	tests := []struct {
+		name     string
+		reserve  string
+		rate     string
+		expected *float64
+	}{
+		{name: "reserve at rate", reserve: "1000", rate: "10", expected: floatPtr(100)},
+		{name: "partial second left", reserve: "5", rate: "10", expected: floatPtr(0.5)},
+		{name: "zero reserve", reserve: "0", rate: "10", expected: floatPtr(0)},
+		{name: "zero rate", reserve: "1000", rate: "0", expected: nil},
+		{name: "negative rate", reserve: "1000", rate: "-1", expected: nil},
+		{name: "missing rate", reserve: "1000", rate: "", expected: nil},
+		{name: "missing reserve", reserve: "", rate: "10", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := calculateRewardSecondsRemaining(tt.reserve, tt.rate)
+			if (result == nil) != (tt.expected == nil) || (result != nil && *result != *tt.expected) {
+				t.Errorf("calculateRewardSecondsRemaining(%q, %q) = %v, expected %v", tt.reserve, tt.rate, formatFloatPtr(result), formatFloatPtr(tt.expected))
+			}
+		})
+	}
+}
+
+func TestIsRewardDepleted(t *testing.T) {
+	tests := []struct {
+		name     string
+		reserve  string
+		rate     string
+		expected bool
+	}{
+		{name: "reserve covers many seconds", reserve: "1000", rate: "10", expected: false},
+		{name: "reserve covers exactly one second", reserve: "10", rate: "10", expected: false},
+		{name: "reserve below one second", reserve: "9", rate: "10", expected: true},
+		{name: "zero reserve", reserve: "0", rate: "10", expected: true},
+		{name: "zero rate is not distributing", reserve: "0", rate: "0", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := isRewardDepleted(tt.reserve, tt.rate); result != tt.expected {
+				t.Errorf("isRewardDepleted(%q, %q) = %v, expected %v", tt.reserve, tt.rate, result, tt.expected)
+			}
+		})
+	}
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+func formatFloatPtr(value *float64) string {
+	if value == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("%f", *value)
+}
+
+func TestCalculateFarmingStatus(t *testing.T) {
+	now := time.Unix(1760572800, 0)
+	nowUnix := strconv.FormatInt(now.Unix(), 10)
+	before := strconv.FormatInt(now.Unix()-1, 10)
+	after := strconv.FormatInt(now.Unix()+1, 10)
+
+	tests := []struct {
+		name     string
+		farming  types.EternalFarming
+		expected string
+	}{
+		{name: "running", farming: types.EternalFarming{StartTime: before, EndTime: after}, expected: models.FarmingStatusActive},
+		{name: "no start or end time", farming: types.EternalFarming{}, expected: models.FarmingStatusActive},
+		{name: "zero end time runs until deactivated", farming: types.EternalFarming{StartTime: before, EndTime: "0"}, expected: models.FarmingStatusActive},
+		{name: "starts now", farming: types.EternalFarming{StartTime: nowUnix, EndTime: after}, expected: models.FarmingStatusActive},
+		{name: "starts in a second", farming: types.EternalFarming{StartTime: after}, expected: models.FarmingStatusPending},
+		{name: "ends now", farming: types.EternalFarming{StartTime: before, EndTime: nowUnix}, expected: models.FarmingStatusEnded},
+		{name: "ended a second ago", farming: types.EternalFarming{StartTime: before, EndTime: before}, expected: models.FarmingStatusEnded},
+		{name: "deactivated while running", farming: types.EternalFarming{StartTime: before, EndTime: after, IsDeactivated: true}, expected: models.FarmingStatusDeactivated},
+		{name: "deactivated before start", farming: types.EternalFarming{StartTime: after, IsDeactivated: true}, expected: models.FarmingStatusDeactivated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := calculateFarmingStatus(tt.farming, now); result != tt.expected {
+				t.Errorf("calculateFarmingStatus() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFarmingRewardsBreakdown(t *testing.T) {
+	s := &APRService{}
+	tokens := map[string]types.Token{
+		"0xreward": {ID: "0xreward", Symbol: "RWD", Decimals: "18", DerivedMatic: "2"},
+		"0xbonus":  {ID: "0xbonus", Symbol: "BNS", Decimals: "6", DerivedMatic: "0.5"},
+	}
+	farming := types.EternalFarming{
+		RewardToken:      "0xreward",
+		BonusRewardToken: "0xbonus",
+		RewardRate:       "1000000000000000000", // 1 RWD per second
+		BonusRewardRate:  "4000000",             // 4 BNS per second
+		RewardReserve0:   "86400000000000000000000",
+		RewardReserve1:   "86400000000",
+	}
+
+	rewards := s.calculateFarmingRewardsFromData(farming, tokens)
+	if len(rewards) != 2 {
+		t.Fatalf("calculateFarmingRewardsFromData() returned %d rewards, expected 2", len(rewards))
+	}
+	if rewards[0].IsBonus || rewards[0].TokensPerSecond != 1 || rewards[0].NativePerSecond != 2 {
+		t.Errorf("main reward = %+v, expected 1 token and 2 native per second", rewards[0])
+	}
+	if !rewards[1].IsBonus || rewards[1].TokensPerSecond != 4 || rewards[1].NativePerSecond != 2 {
+		t.Errorf("bonus reward = %+v, expected 4 tokens and 2 native per second", rewards[1])
+	}
+
+	// Per token APRs add up to the farming APR
+	tvl := 1e6
+	rewardAPRs := calculateRewardAPR(rewards[0].NativePerSecond, tvl) + calculateRewardAPR(rewards[1].NativePerSecond, tvl)
+	if farmingAPR := calculateRewardAPR(s.calculateFarmingRewardRateFromData(farming, tokens), tvl); math.Abs(rewardAPRs-farmingAPR) > 1e-9 {
+		t.Errorf("reward APRs sum to %f, expected the farming APR %f", rewardAPRs, farmingAPR)
+	}
+	if apr := calculateRewardAPR(2, 365*24*60*60*2); apr != 100 {
+		t.Errorf("calculateRewardAPR() = %f, expected 100", apr)
+	}
+	if apr := calculateRewardAPR(2, 0); apr != 0 {
+		t.Errorf("calculateRewardAPR() without TVL = %f, expected 0", apr)
+	}
+
+	// A depleted bonus reserve is kept in the breakdown with a zero rate
+	farming.RewardReserve1 = "3999999"
+	rewards = s.calculateFarmingRewardsFromData(farming, tokens)
+	if len(rewards) != 2 || rewards[1].TokensPerSecond != 0 || rewards[1].NativePerSecond != 0 {
+		t.Errorf("depleted bonus reward = %+v, expected a zero rate", rewards)
+	}
+
+	// Farmings without a bonus token only return the main reward
+	farming.BonusRewardToken = "0x0000000000000000000000000000000000000000"
+	if rewards = s.calculateFarmingRewardsFromData(farming, tokens); len(rewards) != 1 {
+		t.Errorf("calculateFarmingRewardsFromData() without bonus returned %d rewards, expected 1", len(rewards))
+	}
+}
+
+func TestCalculateFarmingMarginalAPR(t *testing.T) {
+	s := &APRService{}
+	year := 365 * 24 * 60 * 60.0
+
+	tests := []struct {
+		name         string
+		rewardRate   float64
+		tvl          float64
+		depositValue float64
+		expected     float64
+	}{
+		{"deposit dilutes the farming TVL", 1, year / 2, year / 2, 100},
+		{"deposit into an empty farming earns all rewards", 2, 0, year, 200},
+		{"large deposit", 1, year, 3 * year, 25},
+		{"no rewards", 0, year, year, 0},
+		{"negative reward rate", -1, year, year, 0},
+		{"no TVL and no deposit", 1, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apr := s.calculateFarmingMarginalAPR(tt.rewardRate, tt.tvl, tt.depositValue)
+			if math.Abs(apr-tt.expected) > 1e-9 {
+				t.Errorf("calculateFarmingMarginalAPR(%v, %v, %v) = %f, expected %f", tt.rewardRate, tt.tvl, tt.depositValue, apr, tt.expected)
+			}
+		})
+	}
+
+	// The marginal APR is the farming APR with the deposit added to the TVL
+	if apr, expected := s.calculateFarmingMarginalAPR(3, 1e6, 5e5), calculateRewardAPR(3, 1.5e6); math.Abs(apr-expected) > 1e-9 {
+		t.Errorf("calculateFarmingMarginalAPR() = %f, expected the APR at TVL plus deposit %f", apr, expected)
+	}
+}
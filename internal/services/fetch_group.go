@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// fetchGroup runs independent fetches concurrently, at most limit at a time.
+// The first failing fetch cancels the context of the others and is returned by Wait.
+type fetchGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	slots  chan struct{}
+	wg     sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+}
+
+func newFetchGroup(ctx context.Context, limit int) *fetchGroup {
+	if limit < 1 {
+		limit = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	return &fetchGroup{
+		ctx:    ctx,
+		cancel: cancel,
+		slots:  make(chan struct{}, limit),
+	}
+}
+
+// Go runs fetch in a goroutine once a slot is free. Fetches still waiting for a slot
+// when the group is cancelled are skipped.
+func (g *fetchGroup) Go(fetch func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		select {
+		case g.slots <- struct{}{}:
+		case <-g.ctx.Done():
+			g.fail(g.ctx.Err())
+			return
+		}
+		defer func() { <-g.slots }()
+
+		if err := fetch(g.ctx); err != nil {
+			g.fail(err)
+		}
+	}()
+}
+
+// Wait waits for all fetches and returns the first error
+func (g *fetchGroup) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
+
+func (g *fetchGroup) fail(err error) {
+	g.errOnce.Do(func() {
+		g.err = err
+		g.cancel()
+	})
+}
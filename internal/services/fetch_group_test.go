@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchGroupLimitsConcurrency(t *testing.T) {
+	var running, maxRunning atomic.Int32
+
+	fetches := newFetchGroup(context.Background(), 2)
+	for i := 0; i < 6; i++ {
+		fetches.Go(func(ctx context.Context) error {
+			current := running.Add(1)
+			defer running.Add(-1)
+			for {
+				max := maxRunning.Load()
+				if current <= max || maxRunning.CompareAndSwap(max, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})
+	}
+
+	if err := fetches.Wait(); err != nil {
+		t.Fatalf("Wait() returned error: %v", err)
+	}
+	if maxRunning.Load() > 2 {
+		t.Errorf("%d fetches ran concurrently, expected at most 2", maxRunning.Load())
+	}
+}
+
+func TestFetchGroupCancelsOnFirstError(t *testing.T) {
+	failure := errors.New("subgraph down")
+
+	fetches := newFetchGroup(context.Background(), 4)
+	fetches.Go(func(ctx context.Context) error {
+		return failure
+	})
+	fetches.Go(func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return errors.New("fetch was not cancelled")
+		}
+	})
+
+	if err := fetches.Wait(); !errors.Is(err, failure) {
+		t.Errorf("Wait() error = %v, expected the first failure", err)
+	}
+}
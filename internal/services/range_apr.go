@@ -0,0 +1,123 @@
+package services
+
+import (
+	"algebra-apr-backend/internal/logger"
+	"algebra-apr-backend/internal/models"
+	"algebra-apr-backend/internal/utils"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// rangePreset is a standard symmetric range around the current price offered by the deposit UI
+type rangePreset struct {
+	Name    string
+	Percent float64 // ±percent of the current price, 0 for full range
+}
+
+var rangePresets = []rangePreset{
+	{Name: "full"},
+	{Name: "50", Percent: 50},
+	{Name: "20", Percent: 20},
+	{Name: "10", Percent: 10},
+	{Name: "5", Percent: 5},
+	{Name: "1", Percent: 1},
+}
+
+// rangeTicks returns the tick range of a preset around the current tick, aligned to the pool's tick spacing
+func (p rangePreset) rangeTicks(tick, tickSpacing int) (int, int) {
+	if p.Percent <= 0 {
+		return utils.UsableTickBounds(tickSpacing)
+	}
+	return utils.PriceRangeToTicks(tick, p.Percent, tickSpacing)
+}
+
+// Process theoretical fee and farming APR of the preset ranges of every pool
+func (s *APRService) processPoolsRangeAPR(networkID uint, runAt time.Time) error {
+	logger.Logger.Info("Processing pools range APR")
+
+	var pools []models.Pool
+	if err := s.db.Where("network_id = ?", networkID).Find(&pools).Error; err != nil {
+		return fmt.Errorf("failed to load pools: %w", err)
+	}
+
+	var farmings []models.Farming
+	if err := s.db.Where("network_id = ? AND status = ? AND pool_id IS NOT NULL", networkID, models.FarmingStatusActive).Find(&farmings).Error; err != nil {
+		return fmt.Errorf("failed to load farmings: %w", err)
+	}
+
+	farmingsByPool := make(map[uint][]models.Farming)
+	for _, farming := range farmings {
+		farmingsByPool[*farming.PoolID] = append(farmingsByPool[*farming.PoolID], farming)
+	}
+
+	rangeAPRs := make([]models.PoolRangeAPR, 0, len(pools)*len(rangePresets))
+	for _, pool := range pools {
+		if pool.Tick == nil || pool.Token0DerivedMatic == nil || pool.Token1DerivedMatic == nil {
+			continue
+		}
+
+		for _, preset := range rangePresets {
+			tickLower, tickUpper := preset.rangeTicks(*pool.Tick, pool.TickSpacing)
+			feeAPR, farmingAPR := s.calculateRangeAPR(pool, farmingsByPool[pool.ID], tickLower, tickUpper)
+
+			rangeAPRs = append(rangeAPRs, models.PoolRangeAPR{
+				PoolID:     pool.ID,
+				NetworkID:  networkID,
+				RunAt:      runAt,
+				Range:      preset.Name,
+				TickLower:  tickLower,
+				TickUpper:  tickUpper,
+				FeeAPR:     feeAPR,
+				FarmingAPR: farmingAPR,
+				TotalAPR:   feeAPR + farmingAPR,
+			})
+		}
+	}
+
+	if len(rangeAPRs) > 0 {
+		if err := s.db.CreateInBatches(&rangeAPRs, 500).Error; err != nil {
+			return fmt.Errorf("failed to save pool range APRs: %w", err)
+		}
+	}
+
+	logger.Logger.Info("Completed pools range APR processing", zap.Int("range_aprs", len(rangeAPRs)))
+	return nil
+}
+
+// calculateRangeAPR returns the fee and farming APR earned by a marginal deposit in a range of a pool,
+// at the pool's current fees, reward rates and active liquidity
+func (s *APRService) calculateRangeAPR(pool models.Pool, farmings []models.Farming, tickLower, tickUpper int) (float64, float64) {
+	amount0, amount1, unitValue := unitLiquidityValue(pool, tickLower, tickUpper)
+	if unitValue <= 0 {
+		return 0, 0
+	}
+
+	positionValue := PositionValue{
+		Liquidity: 1,
+		Amount0:   amount0,
+		Amount1:   amount1,
+		Value:     unitValue,
+		InRange:   isInRange(*pool.Tick, tickLower, tickUpper),
+		TickLower: tickLower,
+		TickUpper: tickUpper,
+	}
+
+	feeAPR := 0.0
+	if pool.Fees != nil {
+		activeLiquidity, _ := strconv.ParseFloat(pool.Liquidity, 64)
+		feeAPR = s.calculatePositionFeeAPR(positionValue, *pool.Fees, activeLiquidity)
+	}
+
+	farmingAPR := 0.0
+	for _, farming := range farmings {
+		if farming.RewardRate == nil || farming.ActiveLiquidity == nil {
+			continue
+		}
+		farmingAPR += s.calculatePositionFarmingAPR(positionValue, *farming.RewardRate, *farming.ActiveLiquidity)
+	}
+
+	return feeAPR, farmingAPR
+}
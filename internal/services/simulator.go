@@ -0,0 +1,190 @@
+package services
+
+import (
+	"algebra-apr-backend/internal/models"
+	"algebra-apr-backend/internal/utils"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPoolNotFound is returned when the simulated pool is unknown for the network
+	ErrPoolNotFound = errors.New("pool not found")
+	// ErrPoolStateUnavailable is returned when the pool has not been processed by an APR update yet
+	ErrPoolStateUnavailable = errors.New("pool state not available yet")
+	// ErrInvalidSimulation is returned when the simulation request can't be evaluated
+	ErrInvalidSimulation = errors.New("invalid simulation")
+)
+
+// SimulationRequest describes a hypothetical deposit into a pool.
+// The range is either tickLower/tickUpper or ±rangePercent around the current price.
+type SimulationRequest struct {
+	Network      string   `json:"network" binding:"required"`
+	PoolAddress  string   `json:"pool" binding:"required"`
+	TickLower    *int     `json:"tickLower"`
+	TickUpper    *int     `json:"tickUpper"`
+	RangePercent *float64 `json:"rangePercent"`
+	Amount       float64  `json:"amount" binding:"required,gt=0"`
+	Currency     string   `json:"currency"` // native (default) or usd
+}
+
+// SimulatedFarmingAPR is the projected APR of a single eternal farming of the pool
+type SimulatedFarmingAPR struct {
+	Hash string  `json:"hash"`
+	APR  float64 `json:"apr"`
+}
+
+// SimulationResult is the projected yield of a hypothetical deposit
+type SimulationResult struct {
+	PoolAddress string                `json:"pool"`
+	CurrentTick int                   `json:"current_tick"`
+	TickLower   int                   `json:"tick_lower"`
+	TickUpper   int                   `json:"tick_upper"`
+	InRange     bool                  `json:"in_range"`
+	Value       float64               `json:"value"` // Deposit value in native currency
+	Liquidity   float64               `json:"liquidity"`
+	Amount0     float64               `json:"amount0"`
+	Amount1     float64               `json:"amount1"`
+	FeeAPR      float64               `json:"fee_apr"`
+	FarmingAPR  float64               `json:"farming_apr"`
+	TotalAPR    float64               `json:"total_apr"`
+	Farmings    []SimulatedFarmingAPR `json:"farmings"`
+}
+
+// unitLiquidityScale is the liquidity the exact amounts of a unit of liquidity are computed for,
+// large enough that rounding down to raw token units doesn't lose precision
+const unitLiquidityScale = 1e18
+
+// unitLiquidityValue returns the token amounts and native value of a single unit of liquidity in a range
+// of a pool at its current price, using the pool state stored by the latest APR update
+func unitLiquidityValue(pool models.Pool, tickLower, tickUpper int) (float64, float64, float64) {
+	sqrtPrice, err := currentSqrtPrice(pool.SqrtPrice, *pool.Tick)
+	if err != nil {
+		return 0, 0, 0
+	}
+
+	liquidity := big.NewInt(unitLiquidityScale)
+	rawAmount0, rawAmount1, err := utils.GetAmountsExact(liquidity, tickLower, tickUpper, sqrtPrice)
+	if err != nil {
+		return 0, 0, 0
+	}
+
+	amount0 := utils.ToDecimalAmount(rawAmount0, pool.Token0Decimals) / unitLiquidityScale
+	amount1 := utils.ToDecimalAmount(rawAmount1, pool.Token1Decimals) / unitLiquidityScale
+
+	return amount0, amount1, amount0**pool.Token0DerivedMatic + amount1**pool.Token1DerivedMatic
+}
+
+// Simulate projects the fee and farming APR of a new deposit, accounting for the dilution
+// the new liquidity adds to the pool's and farmings' active liquidity
+func (s *APRService) Simulate(req SimulationRequest) (*SimulationResult, error) {
+	var pool models.Pool
+	result := s.db.Preload("Network").Joins("JOIN networks ON pools.network_id = networks.id").Where("networks.title = ? AND pools.address = ?", req.Network, strings.ToLower(req.PoolAddress)).First(&pool)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrPoolNotFound
+		}
+		return nil, fmt.Errorf("failed to load pool: %w", result.Error)
+	}
+
+	if pool.Tick == nil || pool.Token0DerivedMatic == nil || pool.Token1DerivedMatic == nil {
+		return nil, ErrPoolStateUnavailable
+	}
+
+	tick := *pool.Tick
+	var tickLower, tickUpper int
+	switch {
+	case req.TickLower != nil && req.TickUpper != nil:
+		tickLower, tickUpper = *req.TickLower, *req.TickUpper
+	case req.RangePercent != nil && *req.RangePercent > 0:
+		tickLower, tickUpper = utils.PriceRangeToTicks(tick, *req.RangePercent, pool.TickSpacing)
+	default:
+		return nil, fmt.Errorf("%w: either tickLower and tickUpper or rangePercent is required", ErrInvalidSimulation)
+	}
+
+	minTick, maxTick := utils.UsableTickBounds(pool.TickSpacing)
+	if tickLower >= tickUpper || tickLower < minTick || tickUpper > maxTick {
+		return nil, fmt.Errorf("%w: invalid tick range %d - %d", ErrInvalidSimulation, tickLower, tickUpper)
+	}
+	if utils.FloorTick(tickLower, pool.TickSpacing) != tickLower || utils.FloorTick(tickUpper, pool.TickSpacing) != tickUpper {
+		return nil, fmt.Errorf("%w: ticks %d - %d are not multiples of the pool tick spacing %d", ErrInvalidSimulation, tickLower, tickUpper, pool.TickSpacing)
+	}
+
+	value := req.Amount
+	if req.Currency == "usd" {
+		if pool.Network.NativePriceUSD == nil || *pool.Network.NativePriceUSD <= 0 {
+			return nil, fmt.Errorf("%w: native price in USD is not available for network %s", ErrInvalidSimulation, req.Network)
+		}
+		value = req.Amount / *pool.Network.NativePriceUSD
+	}
+
+	simulation := &SimulationResult{
+		PoolAddress: pool.Address,
+		CurrentTick: tick,
+		TickLower:   tickLower,
+		TickUpper:   tickUpper,
+		InRange:     isInRange(tick, tickLower, tickUpper),
+		Value:       value,
+		Farmings:    make([]SimulatedFarmingAPR, 0),
+	}
+
+	// Value of a single unit of liquidity in the range decides how much liquidity the deposit buys
+	amount0, amount1, unitValue := unitLiquidityValue(pool, tickLower, tickUpper)
+	if unitValue <= 0 {
+		return simulation, nil
+	}
+
+	liquidity := value / unitValue
+	simulation.Liquidity = liquidity
+	simulation.Amount0 = amount0 * liquidity
+	simulation.Amount1 = amount1 * liquidity
+
+	positionValue := PositionValue{
+		Liquidity: liquidity,
+		Amount0:   simulation.Amount0,
+		Amount1:   simulation.Amount1,
+		Value:     value,
+		InRange:   simulation.InRange,
+	}
+
+	// Fee APR with the new liquidity added to the pool's active liquidity
+	if pool.Fees != nil {
+		activeLiquidity, _ := strconv.ParseFloat(pool.Liquidity, 64)
+		if simulation.InRange {
+			activeLiquidity += liquidity
+		}
+		simulation.FeeAPR = s.calculatePositionFeeAPR(positionValue, *pool.Fees, activeLiquidity)
+	}
+
+	// Farming APR of every farming of the pool with the new liquidity deposited
+	var farmings []models.Farming
+	if err := s.db.Where("pool_id = ? AND status = ?", pool.ID, models.FarmingStatusActive).Find(&farmings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load farmings: %w", err)
+	}
+
+	for _, farming := range farmings {
+		if farming.RewardRate == nil {
+			continue
+		}
+
+		activeLiquidity := 0.0
+		if farming.ActiveLiquidity != nil {
+			activeLiquidity = *farming.ActiveLiquidity
+		}
+		if simulation.InRange {
+			activeLiquidity += liquidity
+		}
+
+		apr := s.calculatePositionFarmingAPR(positionValue, *farming.RewardRate, activeLiquidity)
+		simulation.Farmings = append(simulation.Farmings, SimulatedFarmingAPR{Hash: farming.Hash, APR: apr})
+		simulation.FarmingAPR += apr
+	}
+
+	simulation.TotalAPR = simulation.FeeAPR + simulation.FarmingAPR
+	return simulation, nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"algebra-apr-backend/internal/logger"
+	"algebra-apr-backend/internal/types"
+	"algebra-apr-backend/internal/utils"
+	"math/big"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// PositionValue is the valuation of a single position at the pool's current price
+type PositionValue struct {
+	Liquidity float64 // Position liquidity, used for share of active liquidity
+	Amount0   float64 // Token0 amount in token units
+	Amount1   float64 // Token1 amount in token units
+	Value     float64 // Position value in native currency
+	InRange   bool    // Whether the current tick is inside the position range
+	TickLower int
+	TickUpper int
+}
+
+// valuePosition values a position in native currency using the derivedMatic price of each pool token.
+// Pools and farmings both value positions through this function so their TVL and APR are comparable.
+func valuePosition(pool types.Pool, position types.Position) PositionValue {
+	tick, _ := strconv.Atoi(pool.Tick)
+	tickLower, _ := strconv.Atoi(position.TickLower.TickIdx)
+	tickUpper, _ := strconv.Atoi(position.TickUpper.TickIdx)
+
+	liquidity, err := utils.ParseLiquidity(position.Liquidity)
+	if err != nil {
+		logger.Logger.Warn("Invalid position liquidity", zap.String("position", position.ID), zap.Error(err))
+		return PositionValue{}
+	}
+
+	liquidityFloat, _ := strconv.ParseFloat(position.Liquidity, 64)
+	value := PositionValue{
+		Liquidity: liquidityFloat,
+		InRange:   isInRange(tick, tickLower, tickUpper),
+		TickLower: tickLower,
+		TickUpper: tickUpper,
+	}
+
+	sqrtPrice, err := currentSqrtPrice(pool.SqrtPrice, tick)
+	if err != nil {
+		logger.Logger.Warn("Invalid pool price", zap.String("pool", pool.ID), zap.Error(err))
+		return value
+	}
+
+	amount0, amount1, err := utils.GetAmountsExact(liquidity, tickLower, tickUpper, sqrtPrice)
+	if err != nil {
+		logger.Logger.Warn("Failed to calculate position amounts", zap.String("position", position.ID), zap.Error(err))
+		return value
+	}
+
+	decimals0, _ := strconv.Atoi(pool.Token0.Decimals)
+	decimals1, _ := strconv.Atoi(pool.Token1.Decimals)
+
+	value.Amount0 = utils.ToDecimalAmount(amount0, decimals0)
+	value.Amount1 = utils.ToDecimalAmount(amount1, decimals1)
+	value.Value = valueTokenAmounts(pool, value.Amount0, value.Amount1)
+
+	return value
+}
+
+// currentSqrtPrice returns the pool's exact sqrt price, derived from its tick when the subgraph value is missing
+func currentSqrtPrice(sqrtPrice string, tick int) (*big.Int, error) {
+	if value, err := utils.ParseSqrtPrice(sqrtPrice); err == nil {
+		return value, nil
+	}
+	return utils.GetSqrtRatioAtTick(tick)
+}
+
+// valueTokenAmounts converts token0 and token1 amounts of a pool to native currency
+func valueTokenAmounts(pool types.Pool, amount0, amount1 float64) float64 {
+	derivedMatic0, _ := strconv.ParseFloat(pool.Token0.DerivedMatic, 64)
+	derivedMatic1, _ := strconv.ParseFloat(pool.Token1.DerivedMatic, 64)
+
+	return amount0*derivedMatic0 + amount1*derivedMatic1
+}
+
+// toUSD converts a native currency value to USD, returning nil when the native price is unknown
+func toUSD(value float64, nativePriceUSD *float64) *float64 {
+	if nativePriceUSD == nil {
+		return nil
+	}
+
+	usd := value * *nativePriceUSD
+	return &usd
+}
+
+// isInRange reports whether a position with the given range is earning at the current tick
+func isInRange(tick, tickLower, tickUpper int) bool {
+	return tickLower < tick && tick < tickUpper
+}
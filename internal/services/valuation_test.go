@@ -0,0 +1,118 @@
+package services
+
+import (
+	"algebra-apr-backend/internal/types"
+	"math"
+	"testing"
+	"time"
+)
+
+// testRunTime is the reference time of the test runs, fixed so fee windows don't move across midnight
+var testRunTime = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+func testPool() types.Pool {
+	return types.Pool{
+		ID:   "0xpool",
+		Tick: "-276300",
+		Token0: types.Token{
+			ID:           "0xtoken0",
+			Decimals:     "18",
+			DerivedMatic: "0.5",
+		},
+		Token1: types.Token{
+			ID:           "0xtoken1",
+			Decimals:     "6",
+			DerivedMatic: "0.5",
+		},
+		Token0Price: "1.0",
+		Liquidity:   "3000000000000000000",
+	}
+}
+
+// testPoolFees returns 10 token0 and 10 token1 of fees on the last complete UTC day before date,
+// with date as the reference time of the fee windows
+func testPoolFees(pool types.Pool, date time.Time) poolFeesData {
+	dayData := types.PoolDayData{FeesToken0: "10", FeesToken1: "10", Date: date.Unix()/86400*86400 - 86400}
+	dayData.Pool.ID = pool.ID
+	return poolFeesData{days: map[string][]types.PoolDayData{pool.ID: {dayData}}, at: date}
+}
+
+func testPositions(pool types.Pool) []types.Position {
+	return []types.Position{
+		{
+			ID:        "1",
+			Liquidity: "1000000000000000000",
+			TickLower: types.Tick{TickIdx: "-276400"},
+			TickUpper: types.Tick{TickIdx: "-276200"},
+			Pool:      pool,
+		},
+		{
+			ID:        "2",
+			Liquidity: "2000000000000000000",
+			TickLower: types.Tick{TickIdx: "-887220"},
+			TickUpper: types.Tick{TickIdx: "887220"},
+			Pool:      pool,
+		},
+		{
+			// Out of range, must not be counted
+			ID:        "3",
+			Liquidity: "5000000000000000000",
+			TickLower: types.Tick{TickIdx: "-276000"},
+			TickUpper: types.Tick{TickIdx: "-275000"},
+			Pool:      pool,
+		},
+	}
+}
+
+func TestPoolAndFarmingTVLAgree(t *testing.T) {
+	s := &APRService{}
+	pool := testPool()
+	positions := testPositions(pool)
+
+	poolTVL := s.calculatePoolTVLFromPositions(pool, positions)
+	farmingTVL := s.calculateFarmingActiveTVLFromPositions(positions)
+
+	if poolTVL <= 0 {
+		t.Fatalf("calculatePoolTVLFromPositions() = %f, expected positive TVL", poolTVL)
+	}
+	if poolTVL != farmingTVL {
+		t.Errorf("pool TVL %f and farming TVL %f differ for the same positions", poolTVL, farmingTVL)
+	}
+}
+
+func TestValuePosition(t *testing.T) {
+	pool := testPool()
+	positions := testPositions(pool)
+
+	inRange := valuePosition(pool, positions[0])
+	if !inRange.InRange {
+		t.Fatalf("valuePosition() expected position %s in range", positions[0].ID)
+	}
+
+	expected := inRange.Amount0*0.5 + inRange.Amount1*0.5
+	if math.Abs(inRange.Value-expected) > 1e-12 {
+		t.Errorf("valuePosition() value = %f, expected %f", inRange.Value, expected)
+	}
+
+	outOfRange := valuePosition(pool, positions[2])
+	if outOfRange.InRange {
+		t.Errorf("valuePosition() expected position %s out of range", positions[2].ID)
+	}
+	if outOfRange.Amount1 != 0 || outOfRange.Amount0 <= 0 {
+		t.Errorf("valuePosition() below-range position should hold only token0, got %f/%f", outOfRange.Amount0, outOfRange.Amount1)
+	}
+}
+
+func TestValuationIsIndependentOfToken0Price(t *testing.T) {
+	s := &APRService{}
+	pool := testPool()
+	positions := testPositions(pool)
+
+	repriced := pool
+	repriced.Token0Price = "1000"
+	repricedPositions := testPositions(repriced)
+
+	if a, b := s.calculatePoolTVLFromPositions(pool, positions), s.calculatePoolTVLFromPositions(repriced, repricedPositions); a != b {
+		t.Errorf("pool TVL depends on token0Price: %f != %f", a, b)
+	}
+}
@@ -12,6 +12,8 @@ type Token struct {
 type Pool struct {
 	ID          string `json:"id"`
 	Tick        string `json:"tick"`
+	TickSpacing string `json:"tickSpacing"`
+	SqrtPrice   string `json:"sqrtPrice"`
 	Token0      Token  `json:"token0"`
 	Token1      Token  `json:"token1"`
 	Token0Price string `json:"token0Price"`
@@ -39,7 +41,14 @@ type EternalFarming struct {
 	BonusRewardToken string `json:"bonusRewardToken"`
 	RewardRate       string `json:"rewardRate"`
 	BonusRewardRate  string `json:"bonusRewardRate"`
-	Pool             string `json:"pool"`
+	RewardReserve0   string `json:"rewardReserve0"`
+	RewardReserve1   string `json:"rewardReserve1"`
+	IsDeactivated    bool   `json:"isDeactivated"`
+	StartTime        string `json:"startTime"`
+	EndTime          string `json:"endTime"`
+	Pool             struct {
+		ID string `json:"id"`
+	} `json:"pool"`
 }
 
 type FarmingDeposit struct {
@@ -57,6 +66,30 @@ type PoolDayData struct {
 	} `json:"pool"`
 }
 
+type PoolHourData struct {
+	ID              string `json:"id"`
+	FeesToken0      string `json:"feesToken0"`
+	FeesToken1      string `json:"feesToken1"`
+	PeriodStartUnix int64  `json:"periodStartUnix"`
+	Pool            struct {
+		ID string `json:"id"`
+	} `json:"pool"`
+}
+
+type Bundle struct {
+	ID            string `json:"id"`
+	MaticPriceUSD string `json:"maticPriceUSD"`
+}
+
+type Block struct {
+	Number    int64 `json:"number"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+type Meta struct {
+	Block Block `json:"block"`
+}
+
 // Response structures
 type PoolsResponse struct {
 	Pools []Pool `json:"pools"`
@@ -81,3 +114,15 @@ type TokensResponse struct {
 type PoolDayDatasResponse struct {
 	PoolDayDatas []PoolDayData `json:"poolDayDatas"`
 }
+
+type PoolHourDatasResponse struct {
+	PoolHourDatas []PoolHourData `json:"poolHourDatas"`
+}
+
+type BundlesResponse struct {
+	Bundles []Bundle `json:"bundles"`
+}
+
+type MetaResponse struct {
+	Meta Meta `json:"_meta"`
+}
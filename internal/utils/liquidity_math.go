@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Tick bounds of Algebra's TickMath
+const (
+	MinTick = -887272
+	MaxTick = 887272
+)
+
+var (
+	// Q96 is the fixed point resolution of sqrt prices (2^96)
+	Q96 = new(big.Int).Lsh(big.NewInt(1), 96)
+
+	// MinSqrtRatio and MaxSqrtRatio are the sqrt prices at MinTick and MaxTick
+	MinSqrtRatio, _ = new(big.Int).SetString("4295128739", 10)
+	MaxSqrtRatio, _ = new(big.Int).SetString("1461446703485210103287273052203988822378723970342", 10)
+
+	q32        = new(big.Int).Lsh(big.NewInt(1), 32)
+	q128       = new(big.Int).Lsh(big.NewInt(1), 128)
+	maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+	// tickRatios[i] is sqrt(1.0001)^-(2^i) as a Q128.128 number, as in TickMath.getSqrtRatioAtTick
+	tickRatios = mustParseHexList(
+		"fffcb933bd6fad37aa2d162d1a594001",
+		"fff97272373d413259a46990580e213a",
+		"fff2e50f5f656932ef12357cf3c7fdcc",
+		"ffe5caca7e10e4e61c3624eaa0941cd0",
+		"ffcb9843d60f6159c9db58835c926644",
+		"ff973b41fa98c081472e6896dfb254c0",
+		"ff2ea16466c96a3843ec78b326b52861",
+		"fe5dee046a99a2a811c461f1969c3053",
+		"fcbe86c7900a88aedcffc83b479aa3a4",
+		"f987a7253ac413176f2b074cf7815e54",
+		"f3392b0822b70005940c7a398e4b70f3",
+		"e7159475a2c29b7443b29c7fa6e889d9",
+		"d097f3bdfd2022b8845ad8f792aa5825",
+		"a9f746462d870fdf8a65dc1f90e061e5",
+		"70d869a156d2a1b890bb3df62baf32f7",
+		"31be135f97d08fd981231505542fcfa6",
+		"9aa508b5b7a84e1c677de54f3e99bc9",
+		"5d6af8dedb81196699c329225ee604",
+		"2216e584f5fa1ea926041bedfe98",
+		"48a170391f7dc42444e8fa2",
+	)
+)
+
+func mustParseHexList(values ...string) []*big.Int {
+	result := make([]*big.Int, len(values))
+	for i, value := range values {
+		n, ok := new(big.Int).SetString(value, 16)
+		if !ok {
+			panic(fmt.Sprintf("invalid hex constant %q", value))
+		}
+		result[i] = n
+	}
+	return result
+}
+
+// GetSqrtRatioAtTick returns sqrt(1.0001^tick) as a Q64.96 number, matching TickMath.getSqrtRatioAtTick
+func GetSqrtRatioAtTick(tick int) (*big.Int, error) {
+	absTick := tick
+	if absTick < 0 {
+		absTick = -absTick
+	}
+	if absTick > MaxTick {
+		return nil, fmt.Errorf("tick %d out of range", tick)
+	}
+
+	ratio := new(big.Int)
+	if absTick&0x1 != 0 {
+		ratio.Set(tickRatios[0])
+	} else {
+		ratio.Set(q128)
+	}
+
+	for i := 1; i < len(tickRatios); i++ {
+		if absTick&(1<<i) != 0 {
+			ratio.Mul(ratio, tickRatios[i])
+			ratio.Rsh(ratio, 128)
+		}
+	}
+
+	if tick > 0 {
+		ratio.Div(maxUint256, ratio)
+	}
+
+	// Divide by 1<<32 rounding up to go from Q128.128 to Q64.96
+	remainder := new(big.Int).Mod(ratio, q32)
+	ratio.Rsh(ratio, 32)
+	if remainder.Sign() != 0 {
+		ratio.Add(ratio, big.NewInt(1))
+	}
+
+	return ratio, nil
+}
+
+// GetAmount0Delta returns the amount of token0 between two sqrt prices for the given liquidity,
+// matching SqrtPriceMath.getAmount0Delta
+func GetAmount0Delta(sqrtRatioA, sqrtRatioB, liquidity *big.Int, roundUp bool) *big.Int {
+	if sqrtRatioA.Cmp(sqrtRatioB) > 0 {
+		sqrtRatioA, sqrtRatioB = sqrtRatioB, sqrtRatioA
+	}
+	if sqrtRatioA.Sign() <= 0 {
+		return new(big.Int)
+	}
+
+	numerator1 := new(big.Int).Lsh(liquidity, 96)
+	numerator2 := new(big.Int).Sub(sqrtRatioB, sqrtRatioA)
+
+	if roundUp {
+		return divRoundingUp(mulDivRoundingUp(numerator1, numerator2, sqrtRatioB), sqrtRatioA)
+	}
+
+	amount := new(big.Int).Mul(numerator1, numerator2)
+	amount.Quo(amount, sqrtRatioB)
+	return amount.Quo(amount, sqrtRatioA)
+}
+
+// GetAmount1Delta returns the amount of token1 between two sqrt prices for the given liquidity,
+// matching SqrtPriceMath.getAmount1Delta
+func GetAmount1Delta(sqrtRatioA, sqrtRatioB, liquidity *big.Int, roundUp bool) *big.Int {
+	if sqrtRatioA.Cmp(sqrtRatioB) > 0 {
+		sqrtRatioA, sqrtRatioB = sqrtRatioB, sqrtRatioA
+	}
+
+	delta := new(big.Int).Sub(sqrtRatioB, sqrtRatioA)
+	if roundUp {
+		return mulDivRoundingUp(liquidity, delta, Q96)
+	}
+
+	amount := new(big.Int).Mul(liquidity, delta)
+	return amount.Quo(amount, Q96)
+}
+
+// GetAmountsForLiquidity returns the token amounts held by liquidity between two sqrt prices at the
+// given current sqrt price, rounding down as the pool does when a position is burned
+func GetAmountsForLiquidity(sqrtRatioX96, sqrtRatioA, sqrtRatioB, liquidity *big.Int) (*big.Int, *big.Int) {
+	if sqrtRatioA.Cmp(sqrtRatioB) > 0 {
+		sqrtRatioA, sqrtRatioB = sqrtRatioB, sqrtRatioA
+	}
+
+	if sqrtRatioX96.Cmp(sqrtRatioA) <= 0 {
+		return GetAmount0Delta(sqrtRatioA, sqrtRatioB, liquidity, false), new(big.Int)
+	}
+	if sqrtRatioX96.Cmp(sqrtRatioB) < 0 {
+		return GetAmount0Delta(sqrtRatioX96, sqrtRatioB, liquidity, false), GetAmount1Delta(sqrtRatioA, sqrtRatioX96, liquidity, false)
+	}
+	return new(big.Int), GetAmount1Delta(sqrtRatioA, sqrtRatioB, liquidity, false)
+}
+
+// GetAmountsExact is the exact counterpart of GetAmounts: it returns the raw token amounts of a position
+// with the given liquidity and tick range at the pool's current sqrt price
+func GetAmountsExact(liquidity *big.Int, tickLower, tickUpper int, sqrtPriceX96 *big.Int) (*big.Int, *big.Int, error) {
+	sqrtLower, err := GetSqrtRatioAtTick(tickLower)
+	if err != nil {
+		return nil, nil, err
+	}
+	sqrtUpper, err := GetSqrtRatioAtTick(tickUpper)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	amount0, amount1 := GetAmountsForLiquidity(sqrtPriceX96, sqrtLower, sqrtUpper, liquidity)
+	return amount0, amount1, nil
+}
+
+// ParseLiquidity parses a uint128 liquidity value as returned by the subgraph
+func ParseLiquidity(value string) (*big.Int, error) {
+	liquidity, ok := new(big.Int).SetString(value, 10)
+	if !ok || liquidity.Sign() < 0 {
+		return nil, fmt.Errorf("invalid liquidity %q", value)
+	}
+	return liquidity, nil
+}
+
+// ParseSqrtPrice parses a Q64.96 sqrt price as returned by the subgraph
+func ParseSqrtPrice(value string) (*big.Int, error) {
+	sqrtPrice, ok := new(big.Int).SetString(value, 10)
+	if !ok || sqrtPrice.Cmp(MinSqrtRatio) < 0 || sqrtPrice.Cmp(MaxSqrtRatio) >= 0 {
+		return nil, fmt.Errorf("invalid sqrt price %q", value)
+	}
+	return sqrtPrice, nil
+}
+
+// ToDecimalAmount converts a raw token amount to token units using the token decimals
+func ToDecimalAmount(amount *big.Int, decimals int) float64 {
+	value := new(big.Float).SetInt(amount)
+	if decimals > 0 {
+		scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+		value.Quo(value, scale)
+	}
+
+	result, _ := value.Float64()
+	return result
+}
+
+func mulDivRoundingUp(a, b, denominator *big.Int) *big.Int {
+	product := new(big.Int).Mul(a, b)
+	quotient, remainder := new(big.Int).QuoRem(product, denominator, new(big.Int))
+	if remainder.Sign() != 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	return quotient
+}
+
+func divRoundingUp(a, b *big.Int) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(a, b, new(big.Int))
+	if remainder.Sign() != 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	return quotient
+}
@@ -0,0 +1,240 @@
+package utils
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func mustBigInt(t *testing.T, value string) *big.Int {
+	t.Helper()
+	n, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		t.Fatalf("invalid big int %q", value)
+	}
+	return n
+}
+
+func TestGetSqrtRatioAtTick(t *testing.T) {
+	tests := []struct {
+		name     string
+		tick     int
+		expected string
+	}{
+		{
+			name:     "min tick",
+			tick:     MinTick,
+			expected: "4295128739",
+		},
+		{
+			name:     "min tick + 1",
+			tick:     MinTick + 1,
+			expected: "4295343490",
+		},
+		{
+			name:     "max tick - 1",
+			tick:     MaxTick - 1,
+			expected: "1461373636630004318706518188784493106690254656249",
+		},
+		{
+			name:     "max tick",
+			tick:     MaxTick,
+			expected: "1461446703485210103287273052203988822378723970342",
+		},
+		{
+			name:     "tick 0",
+			tick:     0,
+			expected: "79228162514264337593543950336",
+		},
+		{
+			name:     "tick 1",
+			tick:     1,
+			expected: "79232123823359799118286999568",
+		},
+		{
+			name:     "tick -1",
+			tick:     -1,
+			expected: "79224201403219477170569942574",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := GetSqrtRatioAtTick(tt.tick)
+			if err != nil {
+				t.Fatalf("GetSqrtRatioAtTick(%d) returned error: %v", tt.tick, err)
+			}
+			if result.Cmp(mustBigInt(t, tt.expected)) != 0 {
+				t.Errorf("GetSqrtRatioAtTick(%d) = %s, expected %s", tt.tick, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetSqrtRatioAtTickOutOfRange(t *testing.T) {
+	for _, tick := range []int{MinTick - 1, MaxTick + 1} {
+		if _, err := GetSqrtRatioAtTick(tick); err == nil {
+			t.Errorf("GetSqrtRatioAtTick(%d) expected error", tick)
+		}
+	}
+}
+
+func TestGetAmountDeltas(t *testing.T) {
+	// Price moving from 1 to 1.21 with 1e18 liquidity, as in the core SqrtPriceMath tests
+	liquidity := mustBigInt(t, "1000000000000000000")
+	sqrtPrice1 := new(big.Int).Set(Q96)
+	sqrtPrice121 := mustBigInt(t, "87150978765690771352898345369")
+
+	tests := []struct {
+		name     string
+		amount   *big.Int
+		expected string
+	}{
+		{"amount0 rounding up", GetAmount0Delta(sqrtPrice1, sqrtPrice121, liquidity, true), "90909090909090910"},
+		{"amount0 rounding down", GetAmount0Delta(sqrtPrice1, sqrtPrice121, liquidity, false), "90909090909090909"},
+		{"amount1 rounding up", GetAmount1Delta(sqrtPrice1, sqrtPrice121, liquidity, true), "100000000000000000"},
+		{"amount1 rounding down", GetAmount1Delta(sqrtPrice1, sqrtPrice121, liquidity, false), "99999999999999999"},
+		{"amount0 zero liquidity", GetAmount0Delta(sqrtPrice1, sqrtPrice121, new(big.Int), true), "0"},
+		{"amount1 equal prices", GetAmount1Delta(sqrtPrice1, sqrtPrice1, liquidity, true), "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.amount.Cmp(mustBigInt(t, tt.expected)) != 0 {
+				t.Errorf("got %s, expected %s", tt.amount, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetAmountsExact(t *testing.T) {
+	tests := []struct {
+		name        string
+		liquidity   string
+		tickLower   int
+		tickUpper   int
+		currentTick int
+	}{
+		{
+			name:        "current price below range",
+			liquidity:   "1000000",
+			tickLower:   1000,
+			tickUpper:   2000,
+			currentTick: 500,
+		},
+		{
+			name:        "current price above range",
+			liquidity:   "1000000",
+			tickLower:   1000,
+			tickUpper:   2000,
+			currentTick: 2500,
+		},
+		{
+			name:        "current price in range",
+			liquidity:   "1000000",
+			tickLower:   1000,
+			tickUpper:   2000,
+			currentTick: 1500,
+		},
+		{
+			name:        "whale position beyond float64 precision",
+			liquidity:   "340282366920938463463374607431768211455",
+			tickLower:   -60000,
+			tickUpper:   60000,
+			currentTick: 12345,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			liquidity := mustBigInt(t, tt.liquidity)
+			sqrtPrice, err := GetSqrtRatioAtTick(tt.currentTick)
+			if err != nil {
+				t.Fatalf("GetSqrtRatioAtTick() returned error: %v", err)
+			}
+			amount0, amount1, err := GetAmountsExact(liquidity, tt.tickLower, tt.tickUpper, sqrtPrice)
+			if err != nil {
+				t.Fatalf("GetAmountsExact() returned error: %v", err)
+			}
+
+			// The float approximation must agree with the exact result up to float64 precision,
+			// or one raw unit lost to on-chain rounding down
+			liquidityFloat, _ := new(big.Float).SetInt(liquidity).Float64()
+			expected0, expected1 := GetAmounts(liquidityFloat, tt.tickLower, tt.tickUpper, tt.currentTick)
+
+			tolerance := 1e-9
+			for _, pair := range []struct {
+				exact    *big.Int
+				expected float64
+			}{{amount0, expected0}, {amount1, expected1}} {
+				exact := ToDecimalAmount(pair.exact, 0)
+				if math.Abs(pair.expected-exact) > math.Max(1, pair.expected*tolerance) {
+					t.Errorf("GetAmountsExact() amount = %f, expected %f", exact, pair.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestGetAmountsExactBetweenTicks(t *testing.T) {
+	liquidity := mustBigInt(t, "1000000000000000000")
+	sqrtAtTick, _ := GetSqrtRatioAtTick(1500)
+	sqrtAtNextTick, _ := GetSqrtRatioAtTick(1501)
+	sqrtBetween := new(big.Int).Add(sqrtAtTick, sqrtAtNextTick)
+	sqrtBetween.Rsh(sqrtBetween, 1)
+
+	atTick0, atTick1, _ := GetAmountsExact(liquidity, 1000, 2000, sqrtAtTick)
+	between0, between1, _ := GetAmountsExact(liquidity, 1000, 2000, sqrtBetween)
+	atNextTick0, atNextTick1, _ := GetAmountsExact(liquidity, 1000, 2000, sqrtAtNextTick)
+
+	// A rising price within the tick swaps token0 for token1
+	if between0.Cmp(atTick0) >= 0 || between0.Cmp(atNextTick0) <= 0 {
+		t.Errorf("amount0 %s between ticks is not between %s and %s", between0, atNextTick0, atTick0)
+	}
+	if between1.Cmp(atTick1) <= 0 || between1.Cmp(atNextTick1) >= 0 {
+		t.Errorf("amount1 %s between ticks is not between %s and %s", between1, atTick1, atNextTick1)
+	}
+}
+
+func TestParseSqrtPrice(t *testing.T) {
+	tests := []struct {
+		value       string
+		expectError bool
+	}{
+		{"79228162514264337593543950336", false},
+		{"4295128739", false},
+		{"4295128738", true},
+		{"1461446703485210103287273052203988822378723970342", true},
+		{"", true},
+		{"-1", true},
+		{"1.5", true},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseSqrtPrice(tt.value)
+		if (err != nil) != tt.expectError {
+			t.Errorf("ParseSqrtPrice(%q) error = %v, expected error %v", tt.value, err, tt.expectError)
+		}
+	}
+}
+
+func TestToDecimalAmount(t *testing.T) {
+	amount := mustBigInt(t, "1234567890000000000000")
+	if result := ToDecimalAmount(amount, 18); math.Abs(result-1234.56789) > 1e-9 {
+		t.Errorf("ToDecimalAmount() = %f, expected %f", result, 1234.56789)
+	}
+	if result := ToDecimalAmount(big.NewInt(42), 0); result != 42 {
+		t.Errorf("ToDecimalAmount() = %f, expected 42", result)
+	}
+}
+
+func TestParseLiquidity(t *testing.T) {
+	if _, err := ParseLiquidity("340282366920938463463374607431768211455"); err != nil {
+		t.Errorf("ParseLiquidity() returned error: %v", err)
+	}
+	for _, value := range []string{"", "-1", "1.5", "abc"} {
+		if _, err := ParseLiquidity(value); err == nil {
+			t.Errorf("ParseLiquidity(%q) expected error", value)
+		}
+	}
+}
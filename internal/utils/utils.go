@@ -2,6 +2,7 @@ package utils
 
 import (
 	"math"
+	"sort"
 )
 
 // Math helper functions for concentrated liquidity calculations
@@ -29,3 +30,109 @@ func GetAmounts(liquidity float64, tickLower, tickUpper, currentTick int) (float
 
 	return amount0, amount1
 }
+
+// TickToPrice returns the price of token0 in token1 units at a tick, adjusted for the token decimals
+func TickToPrice(tick, decimals0, decimals1 int) float64 {
+	return math.Pow(1.0001, float64(tick)) * math.Pow(10, float64(decimals0-decimals1))
+}
+
+// APRToAPY compounds a percentage APR the given number of times per year, returning a percentage APY
+func APRToAPY(apr float64, periodsPerYear int) float64 {
+	if periodsPerYear <= 0 {
+		return apr
+	}
+	return (math.Pow(1+apr/100/float64(periodsPerYear), float64(periodsPerYear)) - 1) * 100
+}
+
+// PriceRangeToTicks returns the tick range covering ±percent of the price at the current tick, rounded outward
+// to multiples of the tick spacing. Ranges reaching zero price or beyond the tick bounds are clamped to the
+// usable tick bounds.
+func PriceRangeToTicks(currentTick int, percent float64, tickSpacing int) (int, int) {
+	minTick, maxTick := UsableTickBounds(tickSpacing)
+
+	tickLower := minTick
+	if percent < 100 {
+		tickLower = FloorTick(currentTick+int(math.Floor(math.Log(1-percent/100)/math.Log(1.0001))), tickSpacing)
+		if tickLower < minTick {
+			tickLower = minTick
+		}
+	}
+
+	tickUpper := CeilTick(currentTick+int(math.Ceil(math.Log(1+percent/100)/math.Log(1.0001))), tickSpacing)
+	if tickUpper > maxTick {
+		tickUpper = maxTick
+	}
+
+	return tickLower, tickUpper
+}
+
+// UsableTickBounds returns the lowest and highest ticks a position can use in a pool with the tick spacing,
+// a non-positive spacing is treated as 1
+func UsableTickBounds(tickSpacing int) (int, int) {
+	if tickSpacing <= 0 {
+		tickSpacing = 1
+	}
+	return MinTick / tickSpacing * tickSpacing, MaxTick / tickSpacing * tickSpacing
+}
+
+// FloorTick rounds a tick down to a multiple of the tick spacing, a non-positive spacing is treated as 1
+func FloorTick(tick, tickSpacing int) int {
+	if tickSpacing <= 0 {
+		return tick
+	}
+	rounded := tick / tickSpacing * tickSpacing
+	if rounded > tick {
+		rounded -= tickSpacing
+	}
+	return rounded
+}
+
+// CeilTick rounds a tick up to a multiple of the tick spacing, a non-positive spacing is treated as 1
+func CeilTick(tick, tickSpacing int) int {
+	if tickSpacing <= 0 {
+		return tick
+	}
+	rounded := tick / tickSpacing * tickSpacing
+	if rounded < tick {
+		rounded += tickSpacing
+	}
+	return rounded
+}
+
+// WeightedPercentile returns the smallest value whose cumulative weight reaches percentile (0-100) of the total weight.
+// Values with a non-positive weight are ignored; 0 is returned when there is nothing to weigh.
+func WeightedPercentile(values, weights []float64, percentile float64) float64 {
+	indexes := make([]int, 0, len(values))
+	totalWeight := 0.0
+	for i := range values {
+		if i < len(weights) && weights[i] > 0 {
+			indexes = append(indexes, i)
+			totalWeight += weights[i]
+		}
+	}
+	if len(indexes) == 0 {
+		return 0
+	}
+
+	sort.Slice(indexes, func(a, b int) bool {
+		return values[indexes[a]] < values[indexes[b]]
+	})
+
+	// The cumulative weight can fall short of the total by float rounding, so the top value
+	// would be skipped next to much larger weights
+	if percentile >= 100 {
+		return values[indexes[len(indexes)-1]]
+	}
+
+	target := totalWeight * math.Max(percentile, 0) / 100
+	cumulativeWeight := 0.0
+	for _, i := range indexes {
+		cumulativeWeight += weights[i]
+		if cumulativeWeight >= target {
+			return values[i]
+		}
+	}
+
+	// Float rounding can leave the cumulative weight just below the total
+	return values[indexes[len(indexes)-1]]
+}
@@ -119,3 +119,191 @@ func TestGetAmounts(t *testing.T) {
 		})
 	}
 }
+
+func TestTickToPrice(t *testing.T) {
+	tests := []struct {
+		name      string
+		tick      int
+		decimals0 int
+		decimals1 int
+		expected  float64
+	}{
+		{"tick 0 same decimals", 0, 18, 18, 1.0},
+		{"positive tick", 1000, 18, 18, 1.1051653531057076},
+		{"18/6 decimals stable pair", -276324, 18, 6, 1.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := TickToPrice(tt.tick, tt.decimals0, tt.decimals1)
+			if math.Abs(result-tt.expected)/tt.expected > 1e-4 {
+				t.Errorf("TickToPrice(%d, %d, %d) = %f, expected %f", tt.tick, tt.decimals0, tt.decimals1, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAPRToAPY(t *testing.T) {
+	tests := []struct {
+		name           string
+		apr            float64
+		periodsPerYear int
+		expected       float64
+	}{
+		{"daily compounding", 100, 365, 171.45674820219728},
+		{"weekly compounding", 10, 52, 10.50647927797661},
+		{"zero APR", 0, 365, 0},
+		{"no compounding", 25, 0, 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := APRToAPY(tt.apr, tt.periodsPerYear); math.Abs(result-tt.expected) > 1e-9 {
+				t.Errorf("APRToAPY(%f, %d) = %f, expected %f", tt.apr, tt.periodsPerYear, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPriceRangeToTicks(t *testing.T) {
+	tests := []struct {
+		name          string
+		currentTick   int
+		percent       float64
+		tickSpacing   int
+		expectedLower int
+		expectedUpper int
+	}{
+		{
+			name:          "10 percent around tick 0",
+			currentTick:   0,
+			percent:       10,
+			tickSpacing:   1,
+			expectedLower: -1054,
+			expectedUpper: 954,
+		},
+		{
+			name:          "1 percent around negative tick",
+			currentTick:   -200000,
+			percent:       1,
+			tickSpacing:   1,
+			expectedLower: -200101,
+			expectedUpper: -199900,
+		},
+		{
+			name:          "full range",
+			currentTick:   1000,
+			percent:       100,
+			tickSpacing:   1,
+			expectedLower: MinTick,
+			expectedUpper: 7932,
+		},
+		{
+			name:          "clamped to max tick",
+			currentTick:   880000,
+			percent:       500,
+			tickSpacing:   1,
+			expectedLower: MinTick,
+			expectedUpper: MaxTick,
+		},
+		{
+			name:          "rounded outward to tick spacing",
+			currentTick:   0,
+			percent:       10,
+			tickSpacing:   60,
+			expectedLower: -1080,
+			expectedUpper: 960,
+		},
+		{
+			name:          "rounded outward around negative tick",
+			currentTick:   -200000,
+			percent:       1,
+			tickSpacing:   60,
+			expectedLower: -200160,
+			expectedUpper: -199860,
+		},
+		{
+			name:          "clamped to usable ticks",
+			currentTick:   880000,
+			percent:       500,
+			tickSpacing:   60,
+			expectedLower: -887220,
+			expectedUpper: 887220,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tickLower, tickUpper := PriceRangeToTicks(tt.currentTick, tt.percent, tt.tickSpacing)
+			if tickLower != tt.expectedLower || tickUpper != tt.expectedUpper {
+				t.Errorf("PriceRangeToTicks(%d, %f, %d) = (%d, %d), expected (%d, %d)", tt.currentTick, tt.percent, tt.tickSpacing, tickLower, tickUpper, tt.expectedLower, tt.expectedUpper)
+			}
+		})
+	}
+}
+
+func TestTickRounding(t *testing.T) {
+	tests := []struct {
+		tick          int
+		tickSpacing   int
+		expectedFloor int
+		expectedCeil  int
+	}{
+		{tick: 120, tickSpacing: 60, expectedFloor: 120, expectedCeil: 120},
+		{tick: 61, tickSpacing: 60, expectedFloor: 60, expectedCeil: 120},
+		{tick: -61, tickSpacing: 60, expectedFloor: -120, expectedCeil: -60},
+		{tick: -60, tickSpacing: 60, expectedFloor: -60, expectedCeil: -60},
+		{tick: -7, tickSpacing: 0, expectedFloor: -7, expectedCeil: -7},
+	}
+
+	for _, tt := range tests {
+		if floor := FloorTick(tt.tick, tt.tickSpacing); floor != tt.expectedFloor {
+			t.Errorf("FloorTick(%d, %d) = %d, expected %d", tt.tick, tt.tickSpacing, floor, tt.expectedFloor)
+		}
+		if ceil := CeilTick(tt.tick, tt.tickSpacing); ceil != tt.expectedCeil {
+			t.Errorf("CeilTick(%d, %d) = %d, expected %d", tt.tick, tt.tickSpacing, ceil, tt.expectedCeil)
+		}
+	}
+
+	if minTick, maxTick := UsableTickBounds(60); minTick != -887220 || maxTick != 887220 {
+		t.Errorf("UsableTickBounds(60) = (%d, %d), expected (-887220, 887220)", minTick, maxTick)
+	}
+}
+
+func TestWeightedPercentile(t *testing.T) {
+	values := []float64{50, 10, 1000, 20}
+	weights := []float64{30, 40, 1, 29}
+
+	tests := []struct {
+		name       string
+		percentile float64
+		expected   float64
+	}{
+		{"p25", 25, 10},
+		{"median", 50, 20},
+		{"p75", 75, 50},
+		{"p90", 90, 50},
+		{"max", 100, 1000},
+		{"min", 0, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := WeightedPercentile(values, weights, tt.percentile); result != tt.expected {
+				t.Errorf("WeightedPercentile(%f) = %f, expected %f", tt.percentile, result, tt.expected)
+			}
+		})
+	}
+
+	if result := WeightedPercentile([]float64{5, 100}, []float64{1, 0}, 100); result != 5 {
+		t.Errorf("WeightedPercentile() with zero weight = %f, expected 5", result)
+	}
+	for _, whale := range []float64{1e20, 3e21, 7e23, 1e25} {
+		if result := WeightedPercentile([]float64{5, 100}, []float64{whale, 1e3}, 100); result != 100 {
+			t.Errorf("WeightedPercentile() max next to weight %g = %f, expected 100", whale, result)
+		}
+	}
+	if result := WeightedPercentile(nil, nil, 50); result != 0 {
+		t.Errorf("WeightedPercentile() of no values = %f, expected 0", result)
+	}
+}